@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// WithTokenRefresh returns an Option that authenticates every request with a
+// bearer token from getToken. If the server responds with 401 Unauthorized,
+// refresh is called to obtain a new token, and the request is retried exactly
+// once with whatever getToken then returns. The response to the retry is
+// returned as is, even if it is another 401.
+//
+// If several requests fail with the same stale token at once, only the first
+// calls refresh; the rest wait for it and retry with its token. A refresh
+// error is returned from the round trip.
+//
+// Requests with a body can only be retried if the body can be rewound with
+// GetBody. Otherwise the 401 response is returned.
+//
+// When the client follows a redirect, the token is only sent to the new
+// location if the client's AuthRedirectPolicy allows the Authorization header
+// to be.
+func WithTokenRefresh(getToken func(ctx context.Context) (string, error), refresh func(ctx context.Context) error) Option {
+	var mu sync.Mutex
+	return func(c *Client) *Client {
+		return withNamedMiddleware("token_refresh", func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if orig := originalRequest(req); orig != req && !allowAuthRedirect(c.authRedirect, orig.URL, req.URL) {
+					return next.RoundTrip(req)
+				}
+				ctx := req.Context()
+				token, err := getToken(ctx)
+				if err != nil {
+					return nil, err
+				}
+				first := cloneRequest(req)
+				first.Header.Set("Authorization", "Bearer "+token)
+				resp, err := next.RoundTrip(first)
+				if err != nil || resp.StatusCode != http.StatusUnauthorized {
+					return resp, err
+				}
+				if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+					return resp, nil
+				}
+				drainAndClose(resp.Body)
+
+				mu.Lock()
+				current, err := getToken(ctx)
+				if err == nil && current == token {
+					if err = refresh(ctx); err == nil {
+						current, err = getToken(ctx)
+					}
+				}
+				mu.Unlock()
+				if err != nil {
+					return nil, err
+				}
+
+				retry := cloneRequest(req)
+				retry.Header.Set("Authorization", "Bearer "+current)
+				if req.GetBody != nil {
+					if retry.Body, err = req.GetBody(); err != nil {
+						return nil, err
+					}
+				}
+				return next.RoundTrip(retry)
+			})
+		})(c)
+	}
+}
+
+// originalRequest returns the request that began the chain of redirects req
+// is following, or req itself if it is not following one.
+func originalRequest(req *http.Request) *http.Request {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req
+}
+
+// AuthRedirectPolicy controls whether the Authorization header of a request is
+// sent again when the client follows a redirect.
+type AuthRedirectPolicy int
+
+const (
+	// AuthRedirectSameHost sends the header only to the host, including the
+	// port, of the original request. It is the default.
+	AuthRedirectSameHost AuthRedirectPolicy = iota
+
+	// AuthRedirectSameDomain also sends the header to other hosts in the
+	// same registered domain as the original request, such as from
+	// api.example.com to auth.example.com, as determined by the public
+	// suffix list.
+	AuthRedirectSameDomain
+
+	// AuthRedirectNever never sends the header after a redirect.
+	AuthRedirectNever
+)
+
+func (p AuthRedirectPolicy) String() string {
+	switch p {
+	case AuthRedirectSameHost:
+		return "same_host"
+	case AuthRedirectSameDomain:
+		return "same_domain"
+	case AuthRedirectNever:
+		return "never"
+	}
+	return fmt.Sprintf("AuthRedirectPolicy(%d)", int(p))
+}
+
+// WithAuthRedirectPolicy returns an Option that applies policy to the
+// Authorization header when following redirects. Without this option,
+// AuthRedirectSameHost applies, rather than the standard library's rules,
+// which keep the header for redirects to any subdomain of the original host.
+//
+// Whatever the policy, the header is never sent over plain HTTP once the
+// original request was made over HTTPS. The policy is applied before any
+// function set with WithCheckRedirect or WithMaxRedirects.
+func WithAuthRedirectPolicy(policy AuthRedirectPolicy) Option {
+	return func(c *Client) *Client {
+		c.authRedirect = policy
+		return c
+	}
+}
+
+// checkAuthRedirect wraps the client's CheckRedirect to apply its
+// AuthRedirectPolicy.
+func (c *Client) checkAuthRedirect() {
+	policy, next := c.authRedirect, c.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		orig := via[0]
+		if auth := orig.Header.Values("Authorization"); len(auth) > 0 && allowAuthRedirect(policy, orig.URL, req.URL) {
+			req.Header["Authorization"] = auth
+		} else {
+			req.Header.Del("Authorization")
+		}
+
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+// allowAuthRedirect reports whether policy allows credentials sent to from to
+// be sent to to.
+func allowAuthRedirect(policy AuthRedirectPolicy, from, to *url.URL) bool {
+	if from.Scheme == "https" && to.Scheme != "https" {
+		return false
+	}
+	switch policy {
+	case AuthRedirectSameHost:
+		return canonicalHost(from) == canonicalHost(to)
+	case AuthRedirectSameDomain:
+		a, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(from.Hostname()))
+		if err != nil {
+			return false
+		}
+		b, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(to.Hostname()))
+		return err == nil && a == b
+	}
+	return false
+}
+
+// canonicalHost returns the lowercase host and port of u, adding the default
+// port for its scheme if none is given.
+func canonicalHost(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	return net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
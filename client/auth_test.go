@@ -0,0 +1,202 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+// tokenSource issues numbered tokens, of which only the latest is valid.
+type tokenSource struct {
+	mu        sync.Mutex
+	token     string
+	refreshes atomic.Int64
+}
+
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, nil
+}
+
+func (s *tokenSource) Refresh(ctx context.Context) error {
+	n := s.refreshes.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = "token-" + strconv.FormatInt(n, 10)
+	return nil
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("token-1")
+	var bodies []string
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	src := &tokenSource{token: "token-0"}
+	c := client.New(client.WithTokenRefresh(src.Token, src.Refresh))
+
+	resp, err := c.Post(ts.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d after refreshing, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if got := src.refreshes.Load(); got != 1 {
+		t.Errorf("expected 1 refresh, got %d", got)
+	}
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Errorf("expected the body to be resent, got %q", bodies)
+	}
+
+	// A token that is still rejected after refreshing is not retried again.
+	valid.Store("never")
+	resp, err = c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+	if got := src.refreshes.Load(); got != 2 {
+		t.Errorf("expected exactly one more refresh, got %d", got-1)
+	}
+}
+
+// authEcho returns a server that redirects /redirect to target, and otherwise
+// echoes the Authorization header in X-Echo.
+func authEcho(t *testing.T, target func() string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, target(), http.StatusFound)
+			return
+		}
+		w.Header().Set("X-Echo", r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getWithAuth(t *testing.T, c *client.Client, url string) string {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.Header.Get("X-Echo")
+}
+
+func TestAuthRedirectSameHost(t *testing.T) {
+	var self *httptest.Server
+	self = authEcho(t, func() string { return self.URL + "/echo" })
+	// other has the same hostname as self, but a different port.
+	other := authEcho(t, nil)
+	cross := authEcho(t, func() string { return other.URL + "/echo" })
+
+	c := client.New(client.WithAuthRedirectPolicy(client.AuthRedirectSameHost))
+	if got := getWithAuth(t, c, self.URL+"/redirect"); got != "Bearer secret" {
+		t.Errorf("expected the header on a same-host redirect, got %q", got)
+	}
+	if got := getWithAuth(t, c, cross.URL+"/redirect"); got != "" {
+		t.Errorf("expected no header on a cross-host redirect, got %q", got)
+	}
+
+	c = client.New(client.WithAuthRedirectPolicy(client.AuthRedirectNever))
+	if got := getWithAuth(t, c, self.URL+"/redirect"); got != "" {
+		t.Errorf("expected no header with AuthRedirectNever, got %q", got)
+	}
+}
+
+func TestAuthRedirectSameDomain(t *testing.T) {
+	ts := authEcho(t, func() string { return "http://auth.example.com/echo" })
+
+	// Send every request to ts, whatever its host.
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, ts.Listener.Addr().String())
+	}
+
+	c := client.New(client.WithTransport(tr), client.WithAuthRedirectPolicy(client.AuthRedirectSameDomain))
+	if got := getWithAuth(t, c, "http://api.example.com/redirect"); got != "Bearer secret" {
+		t.Errorf("expected the header on a same-domain redirect, got %q", got)
+	}
+
+	c = client.New(client.WithTransport(tr), client.WithAuthRedirectPolicy(client.AuthRedirectSameHost))
+	if got := getWithAuth(t, c, "http://api.example.com/redirect"); got != "" {
+		t.Errorf("expected no header on a cross-host redirect, got %q", got)
+	}
+}
+
+func TestAuthRedirectDefault(t *testing.T) {
+	ts := authEcho(t, func() string { return "http://auth.example.com/echo" })
+
+	// Send every request to ts, whatever its host.
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, ts.Listener.Addr().String())
+	}
+
+	// The standard library would keep the header for a subdomain.
+	c := client.New(client.WithTransport(tr))
+	if got := getWithAuth(t, c, "http://example.com/redirect"); got != "" {
+		t.Errorf("expected no header on a redirect to a subdomain by default, got %q", got)
+	}
+}
+
+func TestTokenRefreshRedirect(t *testing.T) {
+	var self *httptest.Server
+	self = authEcho(t, func() string { return self.URL + "/echo" })
+	other := authEcho(t, nil)
+	cross := authEcho(t, func() string { return other.URL + "/echo" })
+
+	get := func(c *client.Client, url string) string {
+		t.Helper()
+		resp, err := c.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get("X-Echo")
+	}
+	token := func(ctx context.Context) (string, error) { return "secret", nil }
+	refresh := func(ctx context.Context) error { return nil }
+
+	c := client.New(client.WithTokenRefresh(token, refresh))
+	if got := get(c, self.URL+"/redirect"); got != "Bearer secret" {
+		t.Errorf("expected the token on a same-host redirect, got %q", got)
+	}
+	if got := get(c, cross.URL+"/redirect"); got != "" {
+		t.Errorf("expected no token on a cross-host redirect, got %q", got)
+	}
+
+	c = client.New(client.WithTokenRefresh(token, refresh), client.WithAuthRedirectPolicy(client.AuthRedirectNever))
+	if got := get(c, self.URL+"/redirect"); got != "" {
+		t.Errorf("expected no token after a redirect with AuthRedirectNever, got %q", got)
+	}
+}
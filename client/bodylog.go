@@ -0,0 +1,195 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultBodyLogBytes is the default cap on the number of bytes of a body that
+// WithBodyLogging will log.
+const DefaultBodyLogBytes = 4 << 10
+
+// BodyLogOptions configures WithBodyLogging.
+type BodyLogOptions struct {
+	// Logger receives the logged bodies. It is required.
+	Logger Logger
+
+	// MaxBytes is the maximum number of bytes of each body to log. If zero,
+	// DefaultBodyLogBytes is used.
+	MaxBytes int
+
+	// ContentTypes lists the media types whose bodies are logged. If empty,
+	// only application/json bodies are logged.
+	ContentTypes []string
+
+	// Redact lists JSON fields whose values are replaced with "[REDACTED]"
+	// before logging, as dot-separated paths such as "password" or
+	// "user.token". Paths traverse into arrays, applying to every element.
+	//
+	// Since a truncated body cannot be parsed, bodies larger than MaxBytes are
+	// not logged at all when Redact is set. Only their size is.
+	Redact []string
+}
+
+const redacted = "[REDACTED]"
+
+// WithBodyLogging returns an Option that logs request and response bodies for
+// debugging. Only bodies with one of the configured content types are logged,
+// each up to a size cap, and configured JSON fields are redacted first.
+//
+// Bodies are never consumed on the caller's behalf: the request body is
+// buffered and replaced before sending, and the response body is logged as the
+// caller reads it, once it has been read to the end or closed.
+func WithBodyLogging(opts BodyLogOptions) Option {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultBodyLogBytes
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = []string{"application/json"}
+	}
+
+	return withNamedMiddleware("body_logging", func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body != nil && req.Body != http.NoBody && opts.loggable(req.Header) {
+				prefix, body, err := peek(req.Body, opts.MaxBytes)
+				if err != nil {
+					return nil, err
+				}
+				r := new(http.Request)
+				*r = *req
+				r.Body = body
+				req = r
+				opts.Logger.Info("http request body",
+					"method", req.Method,
+					"url", req.URL.Redacted(),
+					"body", opts.format(prefix),
+				)
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || !opts.loggable(resp.Header) {
+				return resp, err
+			}
+			resp.Body = &loggingBody{
+				ReadCloser: resp.Body,
+				max:        opts.MaxBytes,
+				log: func(b []byte) {
+					opts.Logger.Info("http response body",
+						"method", req.Method,
+						"url", req.URL.Redacted(),
+						"status", resp.StatusCode,
+						"body", opts.format(b),
+					)
+				},
+			}
+			return resp, nil
+		})
+	})
+}
+
+// loggable reports whether a body with the provided headers should be logged.
+func (o BodyLogOptions) loggable(h http.Header) bool {
+	mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && slices.Contains(o.ContentTypes, mt)
+}
+
+// format returns the loggable representation of b, which holds up to
+// MaxBytes+1 bytes of a body.
+func (o BodyLogOptions) format(b []byte) string {
+	truncated := len(b) > o.MaxBytes
+	if truncated {
+		b = b[:o.MaxBytes]
+	}
+	if len(o.Redact) == 0 {
+		if truncated {
+			return string(b) + "...[truncated]"
+		}
+		return string(b)
+	}
+	if truncated {
+		return "[omitted: body larger than cap]"
+	}
+	out, err := redactJSON(b, o.Redact)
+	if err != nil {
+		return "[omitted: body is not valid JSON]"
+	}
+	return string(out)
+}
+
+// peek reads up to max+1 bytes from body, returning them along with a body
+// that yields the complete original content.
+func peek(body io.ReadCloser, max int) ([]byte, io.ReadCloser, error) {
+	prefix, err := io.ReadAll(io.LimitReader(body, int64(max)+1))
+	if err != nil {
+		body.Close()
+		return nil, nil, err
+	}
+	return prefix, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}, nil
+}
+
+// loggingBody captures up to max+1 bytes of the body as it is read and logs
+// them once, when the body reaches EOF or is closed.
+type loggingBody struct {
+	io.ReadCloser
+	max  int
+	log  func([]byte)
+	buf  []byte
+	once sync.Once
+}
+
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.max + 1 - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(n, room)]...)
+	}
+	if err == io.EOF {
+		b.once.Do(func() { b.log(b.buf) })
+	}
+	return n, err
+}
+
+func (b *loggingBody) Close() error {
+	b.once.Do(func() { b.log(b.buf) })
+	return b.ReadCloser.Close()
+}
+
+// redactJSON replaces the values at each of the dot-separated paths in the
+// JSON document b with "[REDACTED]".
+func redactJSON(b []byte, paths []string) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		redactPath(doc, strings.Split(p, "."))
+	}
+	return json.Marshal(doc)
+}
+
+func redactPath(v any, path []string) {
+	switch v := v.(type) {
+	case map[string]any:
+		child, ok := v[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			v[path[0]] = redacted
+			return
+		}
+		redactPath(child, path[1:])
+	case []any:
+		for _, elem := range v {
+			redactPath(elem, path)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package client_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+// testLogger records every message logged through it.
+type testLogger struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *testLogger) log(level, msg string, keyvals ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, fmt.Sprint(level, " ", msg, " ", keyvals))
+}
+
+func (l *testLogger) Info(msg string, keyvals ...any)  { l.log("INFO", msg, keyvals...) }
+func (l *testLogger) Warn(msg string, keyvals ...any)  { l.log("WARN", msg, keyvals...) }
+func (l *testLogger) Error(msg string, keyvals ...any) { l.log("ERROR", msg, keyvals...) }
+
+func (l *testLogger) Entries() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.entries...)
+}
+
+func TestBodyLogging(t *testing.T) {
+	const (
+		reqBody  = `{"user":"alice","password":"hunter2"}`
+		respBody = `{"items":[{"id":1,"token":"abc"},{"id":2,"token":"def"}]}`
+	)
+
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(respBody))
+	}))
+	defer ts.Close()
+
+	l := new(testLogger)
+	c := client.New(client.WithBodyLogging(client.BodyLogOptions{
+		Logger: l,
+		Redact: []string{"password", "items.token"},
+	}))
+
+	resp, err := c.Post(ts.URL, "application/json", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if received != reqBody {
+		t.Errorf("expected server to receive %q, got %q", reqBody, received)
+	}
+	if string(got) != respBody {
+		t.Errorf("expected caller to receive %q, got %q", respBody, got)
+	}
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %v", entries)
+	}
+	if !strings.Contains(entries[0], `body {"password":"[REDACTED]","user":"alice"}`) {
+		t.Errorf("expected redacted request body, got %q", entries[0])
+	}
+	if !strings.Contains(entries[1], `body {"items":[{"id":1,"token":"[REDACTED]"},{"id":2,"token":"[REDACTED]"}]}`) {
+		t.Errorf("expected redacted response body, got %q", entries[1])
+	}
+}
+
+func TestBodyLoggingCap(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	l := new(testLogger)
+	c := client.New(client.WithBodyLogging(client.BodyLogOptions{
+		Logger:       l,
+		MaxBytes:     10,
+		ContentTypes: []string{"text/plain"},
+	}))
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !bytes.Equal(got, body) {
+		t.Errorf("expected the full body, got %d bytes", len(got))
+	}
+	entries := l.Entries()
+	if len(entries) != 1 || !strings.Contains(entries[0], "body aaaaaaaaaa...[truncated]") {
+		t.Errorf("expected a truncated body, got %v", entries)
+	}
+}
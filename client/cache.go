@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+)
+
+// Cache stores serialized responses for WithCache. Implementations must be
+// safe for concurrent use, and may evict entries at any time.
+type Cache interface {
+	// Get returns the response stored under key, if any.
+	Get(key string) ([]byte, bool)
+
+	// Set stores resp under key, replacing any existing entry.
+	Set(key string, resp []byte)
+
+	// Delete removes the entry stored under key, if any.
+	Delete(key string)
+}
+
+// MaxCachedBodySize is the largest response body WithCache stores. Larger
+// responses are passed through to the caller unbuffered.
+const MaxCachedBodySize = 1 << 20
+
+// WithCache returns an Option that caches responses to GET requests that carry
+// an ETag in cache, and revalidates them on every later request for the same
+// URL by sending If-None-Match. If the server answers 304 Not Modified, the
+// cached response is returned in its place, saving the transfer of the body.
+//
+// Every request still reaches the server: responses are never served from the
+// cache without revalidation, whatever their Cache-Control headers say, so a
+// stale response is never returned. Responses with "Cache-Control: no-store"
+// or a Vary header, requests with a Range, Authorization or Cookie header, and
+// requests whose caller set their own conditional headers pass through
+// uncached.
+//
+// Cached bodies are read into memory in full before the response is returned,
+// so bodies over MaxCachedBodySize are not cached.
+func WithCache(cache Cache) Option {
+	return withNamedMiddleware("cache", func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !cacheable(req) {
+				return next.RoundTrip(req)
+			}
+			key := req.URL.String()
+
+			cached := cachedResponse(cache, key, req)
+			if cached != nil {
+				req = cloneRequest(req)
+				req.Header.Set("If-None-Match", cached.Header.Get("ETag"))
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				if cached != nil {
+					cached.Body.Close()
+				}
+				return resp, err
+			}
+
+			if resp.StatusCode == http.StatusNotModified && cached != nil {
+				drainAndClose(resp.Body)
+				cached.Request = req
+				return cached, nil
+			}
+			if cached != nil {
+				cached.Body.Close()
+			}
+
+			switch {
+			case resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == "":
+				cache.Delete(key)
+			case strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store"):
+				cache.Delete(key)
+			case resp.Header.Get("Vary") != "" || resp.ContentLength > MaxCachedBodySize:
+				cache.Delete(key)
+			default:
+				prefix, body, err := peek(resp.Body, MaxCachedBodySize)
+				if err != nil {
+					return nil, err
+				}
+				if len(prefix) > MaxCachedBodySize {
+					resp.Body = body
+					cache.Delete(key)
+					break
+				}
+				body.Close()
+				resp.Body = io.NopCloser(bytes.NewReader(prefix))
+				dump, err := httputil.DumpResponse(resp, true)
+				if err != nil {
+					return nil, err
+				}
+				cache.Set(key, dump)
+			}
+			return resp, nil
+		})
+	})
+}
+
+// cacheable reports whether WithCache may cache the response to req.
+func cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != "" {
+		return false
+	}
+	for _, h := range []string{"Range", "Authorization", "Cookie", "If-None-Match", "If-Modified-Since"} {
+		if req.Header.Get(h) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedResponse returns the response stored in cache under key, or nil if
+// there is none or it cannot be parsed, in which case it is removed.
+func cachedResponse(cache Cache, key string, req *http.Request) *http.Response {
+	b, ok := cache.Get(key)
+	if !ok {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+	if err != nil {
+		cache.Delete(key)
+		return nil
+	}
+	return resp
+}
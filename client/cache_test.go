@@ -0,0 +1,123 @@
+package client_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestMemoryCache(t *testing.T) {
+	var sent, revalidated int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		sent++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("widgets"))
+	}))
+	defer ts.Close()
+
+	c := client.New(client.WithMemoryCache(10, 1<<20))
+	for i := range 3 {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != "widgets" {
+			t.Errorf("request %d: expected 200 %q, got %d %q", i, "widgets", resp.StatusCode, body)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/plain" {
+			t.Errorf("request %d: expected the cached headers, got Content-Type %q", i, ct)
+		}
+	}
+	if sent != 1 || revalidated != 2 {
+		t.Errorf("expected 1 full response and 2 revalidations, got %d and %d", sent, revalidated)
+	}
+}
+
+func TestCacheNoStore(t *testing.T) {
+	var sent int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Error("expected no revalidation of an uncached response")
+		}
+		sent++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-store")
+	}))
+	defer ts.Close()
+
+	cache := client.NewMemoryCache(10, 0)
+	c := client.New(client.WithCache(cache))
+	for range 2 {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if sent != 2 || cache.Len() != 0 {
+		t.Errorf("expected 2 uncached responses, got %d sent and %d cached", sent, cache.Len())
+	}
+}
+
+func TestCacheSkipped(t *testing.T) {
+	large := strings.Repeat("x", client.MaxCachedBodySize+1)
+	for _, tt := range []struct {
+		name   string
+		cookie string
+		vary   string
+		body   string
+	}{
+		{name: "vary", vary: "Accept-Encoding", body: "widgets"},
+		{name: "cookie", cookie: "session=abc", body: "widgets"},
+		{name: "oversized", body: large},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-None-Match") != "" {
+					t.Error("expected no revalidation of an uncached response")
+				}
+				w.Header().Set("ETag", `"v1"`)
+				if tt.vary != "" {
+					w.Header().Set("Vary", tt.vary)
+				}
+				// Flush before writing so the body has no Content-Length.
+				w.(http.Flusher).Flush()
+				io.WriteString(w, tt.body)
+			}))
+			defer ts.Close()
+
+			cache := client.NewMemoryCache(10, 0)
+			c := client.New(client.WithCache(cache))
+			for range 2 {
+				req, _ := http.NewRequest("GET", ts.URL, nil)
+				if tt.cookie != "" {
+					req.Header.Set("Cookie", tt.cookie)
+				}
+				resp, err := c.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if string(body) != tt.body {
+					t.Errorf("expected the full body of %d bytes, got %d", len(tt.body), len(body))
+				}
+			}
+			if cache.Len() != 0 {
+				t.Errorf("expected nothing cached, got %d entries", cache.Len())
+			}
+		})
+	}
+}
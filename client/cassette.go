@@ -0,0 +1,252 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ErrNoInteraction is returned by a replaying Cassette for a request that
+// matches none of its recorded interactions.
+var ErrNoInteraction = errors.New("client: no recorded interaction matches request")
+
+// CassetteMode selects whether a Cassette records or replays.
+type CassetteMode int
+
+const (
+	// CassetteReplay answers requests from the interactions in the cassette
+	// file, without using the network.
+	CassetteReplay CassetteMode = iota
+
+	// CassetteRecord sends requests for real and records each request and
+	// response, to be written to the cassette file by Save.
+	CassetteRecord
+)
+
+// Redacted replaces the values of redacted headers in a cassette file.
+const Redacted = "REDACTED"
+
+// CassetteOptions configures a Cassette.
+type CassetteOptions struct {
+	// Mode selects recording or replaying. The default is CassetteReplay.
+	Mode CassetteMode
+
+	// Transport sends requests while recording. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// MatchHeaders lists request headers that must be equal, in addition to
+	// the method and URL, for a request to match a recorded interaction.
+	MatchHeaders []string
+
+	// MatchBody requires the request body to be equal too.
+	MatchBody bool
+
+	// RedactHeaders lists request and response headers, such as
+	// Authorization and Set-Cookie, whose values are replaced with Redacted
+	// before being written to the file, so secrets are not committed with
+	// the cassette. Matching on a redacted header compares against Redacted.
+	RedactHeaders []string
+}
+
+// Interaction is a request and its response, as stored in a cassette file.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request stored in a cassette file.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the part of a response stored in a cassette file.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// Cassette is a RoundTripper that records HTTP interactions to a JSON file
+// and replays them, for deterministic tests against third-party APIs. Pass it
+// to WithTransport:
+//
+//	cassette, err := client.NewCassette("testdata/widgets.json", client.CassetteOptions{
+//		Mode:          client.CassetteRecord,
+//		RedactHeaders: []string{"Authorization"},
+//	})
+//	c := client.New(client.WithTransport(cassette))
+//	// ... make requests ...
+//	err = cassette.Save()
+//
+// When replaying, each request is answered by the first recorded interaction
+// that matches it and has not yet been used. If every match has been used,
+// the last one is used again, so a request that is repeated more times than
+// it was recorded still gets a response.
+type Cassette struct {
+	path string
+	opts CassetteOptions
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewCassette returns a Cassette for the file at path. When replaying, the
+// file is read immediately, and an error is returned if it cannot be.
+func NewCassette(path string, opts CassetteOptions) (*Cassette, error) {
+	c := &Cassette{path: path, opts: opts}
+	if opts.Mode == CassetteRecord {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.interactions); err != nil {
+		return nil, fmt.Errorf("client: reading cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// RoundTrip implements http.RoundTripper, recording or replaying req.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = cloneRequest(req)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: c.redact(req.Header),
+		Body:   body,
+	}
+	if recorded.Method == "" {
+		recorded.Method = http.MethodGet
+	}
+
+	if c.opts.Mode == CassetteRecord {
+		return c.record(req, recorded)
+	}
+	return c.replay(req, recorded)
+}
+
+func (c *Cassette) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	rt := c.opts.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     c.redact(resp.Header),
+			Body:       body,
+		},
+	})
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	match := -1
+	for i, in := range c.interactions {
+		if !c.matches(recorded, in.Request) {
+			continue
+		}
+		match = i
+		if !c.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+	}
+	c.used[match] = true
+
+	r := c.interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matches reports whether req matches a recorded request.
+func (c *Cassette) matches(req, recorded RecordedRequest) bool {
+	if req.Method != recorded.Method || req.URL != recorded.URL {
+		return false
+	}
+	for _, h := range c.opts.MatchHeaders {
+		if !slices.Equal(req.Header.Values(h), recorded.Header.Values(h)) {
+			return false
+		}
+	}
+	return !c.opts.MatchBody || bytes.Equal(req.Body, recorded.Body)
+}
+
+// redact returns a copy of h with the values of the redacted headers
+// replaced.
+func (c *Cassette) redact(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range c.opts.RedactHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, Redacted)
+		}
+	}
+	return h
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing
+// when replaying.
+func (c *Cassette) Save() error {
+	if c.opts.Mode != CassetteRecord {
+		return nil
+	}
+	c.mu.Lock()
+	b, err := json.MarshalIndent(c.interactions, "", "\t")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,115 @@
+package client_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Method", r.Method)
+		w.Write([]byte(r.URL.Path + ":" + string(body)))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := client.NewCassette(path, client.CassetteOptions{
+		Mode:          client.CassetteRecord,
+		RedactHeaders: []string{"Authorization", "Set-Cookie"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(client.WithTransport(recorder))
+
+	req, _ := http.NewRequest("GET", ts.URL+"/widgets", nil)
+	req.Header.Set("Authorization", "Bearer hunter2")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	resp, err = c.Post(ts.URL+"/widgets", "text/plain", strings.NewReader("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(file), "hunter2") || strings.Contains(string(file), "secret") {
+		t.Errorf("expected secrets to be redacted, got %s", file)
+	}
+
+	// The server is gone, so replayed responses can only come from the file.
+	player, err := client.NewCassette(path, client.CassetteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = client.New(client.WithTransport(player))
+	for _, tt := range []struct {
+		method, body, want string
+	}{
+		{"POST", "new", "/widgets:new"},
+		{"GET", "", "/widgets:"},
+	} {
+		req, _ := http.NewRequest(tt.method, ts.URL+"/widgets", strings.NewReader(tt.body))
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.want || resp.Header.Get("X-Method") != tt.method {
+			t.Errorf("%s: expected body %q, got %q", tt.method, tt.want, body)
+		}
+		if got := resp.Header.Get("Set-Cookie"); got != client.Redacted {
+			t.Errorf("%s: expected a redacted cookie, got %q", tt.method, got)
+		}
+	}
+
+	_, err = c.Get(ts.URL + "/gadgets")
+	if !errors.Is(err, client.ErrNoInteraction) {
+		t.Errorf("expected error %v, got %v", client.ErrNoInteraction, err)
+	}
+}
+
+func TestCassetteMatchBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	os.WriteFile(path, []byte(`[
+		{"request": {"method": "POST", "url": "http://api.test/q", "body": "YQ=="}, "response": {"status_code": 200, "body": "Zmlyc3Q="}},
+		{"request": {"method": "POST", "url": "http://api.test/q", "body": "Yg=="}, "response": {"status_code": 200, "body": "c2Vjb25k"}}
+	]`), 0o644)
+
+	player, err := client.NewCassette(path, client.CassetteOptions{MatchBody: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(client.WithTransport(player))
+	for _, tt := range []struct{ body, want string }{{"b", "second"}, {"a", "first"}, {"b", "second"}} {
+		resp, err := c.Post("http://api.test/q", "text/plain", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.want {
+			t.Errorf("body %q: expected %q, got %q", tt.body, tt.want, body)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ErrChecksumMismatch is returned by a VerifyingReader, and by Download, when
+// the data read does not have the expected checksum.
+var ErrChecksumMismatch = errors.New("client: checksum mismatch")
+
+// ExpectedChecksum is the digest that data verified by a VerifyingReader or
+// Download must have. The zero value verifies nothing.
+type ExpectedChecksum struct {
+	algo string
+	sum  []byte
+	err  error
+}
+
+// WithExpectedChecksum returns an ExpectedChecksum for data whose digest,
+// using algo, is the hex encoded hexsum. The supported algorithms are
+// "sha256", "sha512" and "sha512_256".
+//
+// An unsupported algo or malformed hexsum is reported when the checksum is
+// used.
+func WithExpectedChecksum(algo, hexsum string) ExpectedChecksum {
+	sum, err := hex.DecodeString(hexsum)
+	if err != nil {
+		err = fmt.Errorf("client: invalid %s checksum %q: %w", algo, hexsum, err)
+	}
+	return ExpectedChecksum{algo: algo, sum: sum, err: err}
+}
+
+func (c ExpectedChecksum) isZero() bool {
+	return c.algo == "" && c.sum == nil && c.err == nil
+}
+
+// newHash returns a hash for the checksum's algorithm.
+func (c ExpectedChecksum) newHash() (hash.Hash, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	var h hash.Hash
+	switch c.algo {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	case "sha512_256":
+		h = sha512.New512_256()
+	default:
+		return nil, fmt.Errorf("client: unsupported checksum algorithm %q", c.algo)
+	}
+	if len(c.sum) != h.Size() {
+		return nil, fmt.Errorf("client: %s checksum must be %d bytes, got %d", c.algo, h.Size(), len(c.sum))
+	}
+	return h, nil
+}
+
+// verify returns ErrChecksumMismatch, wrapped with both digests, if h does not
+// hold the expected checksum.
+func (c ExpectedChecksum) verify(h hash.Hash) error {
+	if got := h.Sum(nil); !bytes.Equal(got, c.sum) {
+		return fmt.Errorf("%w: %s is %x, expected %x", ErrChecksumMismatch, c.algo, got, c.sum)
+	}
+	return nil
+}
+
+// VerifyingReader returns a reader that reads from r, hashing the data as it
+// goes. When r is exhausted, the reader returns ErrChecksumMismatch instead of
+// io.EOF if the data did not have the expected checksum, so callers must read
+// to the end before trusting what they have read.
+func VerifyingReader(r io.Reader, sum ExpectedChecksum) io.Reader {
+	h, err := sum.newHash()
+	return &verifyingReader{r: r, h: h, sum: sum, err: err}
+}
+
+type verifyingReader struct {
+	r   io.Reader
+	h   hash.Hash
+	sum ExpectedChecksum
+	err error
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF {
+		if verr := v.sum.verify(v.h); verr != nil {
+			err = verr
+		}
+		v.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,71 @@
+package client_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestVerifyingReader(t *testing.T) {
+	sum := sha512.Sum512(artifact)
+	good := client.WithExpectedChecksum("sha512", hex.EncodeToString(sum[:]))
+	got, err := io.ReadAll(client.VerifyingReader(strings.NewReader(string(artifact)), good))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(artifact) {
+		t.Errorf("expected %d bytes, got %d", len(artifact), len(got))
+	}
+
+	bad := client.WithExpectedChecksum("sha512", strings.Repeat("00", sha512.Size))
+	_, err = io.ReadAll(client.VerifyingReader(strings.NewReader(string(artifact)), bad))
+	if !errors.Is(err, client.ErrChecksumMismatch) {
+		t.Errorf("expected error %v, got %v", client.ErrChecksumMismatch, err)
+	}
+
+	_, err = io.ReadAll(client.VerifyingReader(strings.NewReader(""), client.WithExpectedChecksum("md5", "00")))
+	if err == nil || errors.Is(err, client.ErrChecksumMismatch) {
+		t.Errorf("expected an unsupported algorithm error, got %v", err)
+	}
+}
+
+func TestDownloadChecksum(t *testing.T) {
+	var gotRange string
+	ts := artifactServer(t, &gotRange)
+	dir := t.TempDir()
+	sum := sha256.Sum256(artifact)
+
+	dest := filepath.Join(dir, "good.bin")
+	err := client.New().Download(context.Background(), ts.URL, dest, client.DownloadOptions{
+		Checksum: client.WithExpectedChecksum("sha256", hex.EncodeToString(sum[:])),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("expected the download to exist, got %v", err)
+	}
+
+	dest = filepath.Join(dir, "bad.bin")
+	err = client.New().Download(context.Background(), ts.URL, dest, client.DownloadOptions{
+		Resume:   true,
+		Checksum: client.WithExpectedChecksum("sha256", strings.Repeat("ab", sha256.Size)),
+	})
+	if !errors.Is(err, client.ErrChecksumMismatch) {
+		t.Fatalf("expected error %v, got %v", client.ErrChecksumMismatch, err)
+	}
+	for _, name := range []string{dest, dest + ".part"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to exist, got %v", filepath.Base(name), err)
+		}
+	}
+}
@@ -3,8 +3,16 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"slices"
 	"time"
+
+	"github.com/haleyrc/http/internal/clock"
 )
 
 // DefaultTimeout is 5s and is used if no other timeout is provided.
@@ -14,6 +22,22 @@ const DefaultTimeout = 5 * time.Second
 // default for timeout.
 type Client struct {
 	*http.Client
+
+	middleware      []layer
+	attempt         []layer
+	outer           []layer
+	propagatePanics bool
+	clock           clock.Clock
+	retry           *RetryPolicy
+	retryBudget     *retryBudget
+	authRedirect    AuthRedirectPolicy
+	checkRedirect   bool
+	proxy           string
+	forceClose      bool
+	dialWrappers    []func(conn net.Conn) net.Conn
+
+	// base is the RoundTripper beneath the middleware.
+	base http.RoundTripper
 }
 
 // Option is passed to New to modify the default parameters for things like
@@ -47,6 +71,27 @@ func WithCheckRedirect(f func(req *http.Request, via []*http.Request) error) Opt
 	}
 }
 
+// ErrTooManyRedirects is returned, wrapped in a *url.Error, when a request
+// exceeds the limit set by WithMaxRedirects.
+var ErrTooManyRedirects = errors.New("client: too many redirects")
+
+// WithMaxRedirects returns an Option that limits the number of redirects the
+// client follows for a single request to n. If n is zero, redirects are not
+// followed at all and the 3xx response is returned to the caller. If n is
+// negative, redirects are followed without limit. Without this option, the
+// client gives up after 10 requests, which is 9 redirects.
+func WithMaxRedirects(n int) Option {
+	return WithCheckRedirect(func(req *http.Request, via []*http.Request) error {
+		switch {
+		case n == 0:
+			return http.ErrUseLastResponse
+		case n > 0 && len(via) > n:
+			return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, n)
+		}
+		return nil
+	})
+}
+
 // WithJar returns an Option that sets the client's cookie jar to the provided
 // value.
 func WithJar(j http.CookieJar) Option {
@@ -56,6 +101,149 @@ func WithJar(j http.CookieJar) Option {
 	}
 }
 
+// WithDisableHTTP2 returns an Option that prevents the client from negotiating
+// HTTP/2, forcing HTTP/1.1 for all requests.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithDisableHTTP2() Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil {
+			t.ForceAttemptHTTP2 = false
+			t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+			if t.TLSClientConfig != nil {
+				t.TLSClientConfig = t.TLSClientConfig.Clone()
+				t.TLSClientConfig.NextProtos = slices.DeleteFunc(t.TLSClientConfig.NextProtos, func(p string) bool {
+					return p == "h2"
+				})
+			}
+		}
+		return c
+	}
+}
+
+// WithHTTP2HealthCheck returns an Option that makes the client check that its
+// pooled HTTP/2 connections are still alive. A connection that goes silently
+// dead, such as when a NAT gateway or load balancer drops it without telling
+// either end, otherwise stays in the pool, and every request sent on it stalls
+// until it times out, since HTTP/2 sends all requests to a host over one
+// connection. With this option, once no frame has been received on a
+// connection for readIdle, the client sends a PING, and if no reply arrives
+// within pingTimeout, it closes the connection so later requests dial a new
+// one. Requests already in flight on the connection fail.
+//
+// A readIdle of zero disables the check. A pingTimeout of zero uses a default
+// of 15s. HTTP/1.1 connections are not affected.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithHTTP2HealthCheck(readIdle, pingTimeout time.Duration) Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil {
+			if t.HTTP2 == nil {
+				t.HTTP2 = new(http.HTTP2Config)
+			} else {
+				cfg := *t.HTTP2
+				t.HTTP2 = &cfg
+			}
+			t.HTTP2.SendPingTimeout = readIdle
+			t.HTTP2.PingTimeout = pingTimeout
+		}
+		return c
+	}
+}
+
+// WithMaxConnsPerHost returns an Option that limits the number of connections,
+// whether dialing, active or idle, the client keeps open to any one host to n.
+// Once the limit is reached, further requests to that host wait for a
+// connection to become free, or until their context is done. The number of
+// idle connections kept per host is lowered to n if it is higher.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithMaxConnsPerHost(n int) Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil {
+			t.MaxConnsPerHost = n
+			idle := t.MaxIdleConnsPerHost
+			if idle == 0 {
+				idle = http.DefaultMaxIdleConnsPerHost
+			}
+			if n > 0 && idle > n {
+				t.MaxIdleConnsPerHost = n
+			}
+		}
+		return c
+	}
+}
+
+// Clock provides the current time and timers to the client. It exists so tests
+// can control time-dependent behavior, and defaults to the system clock.
+type Clock = clock.Clock
+
+// Timer is the timer type returned by a Clock.
+type Timer = clock.Timer
+
+// WithClock returns an Option that makes the client use clk for all
+// time-dependent behavior. This is intended for tests.
+func WithClock(clk Clock) Option {
+	return func(c *Client) *Client {
+		c.clock = clk
+		return c
+	}
+}
+
+// transport returns the client's *http.Transport for options to modify,
+// cloning the default transport if none has been set yet. It returns nil if a
+// RoundTripper other than an *http.Transport has been provided.
+func (c *Client) transport() *http.Transport {
+	if c.Transport == nil {
+		c.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	t, _ := c.Transport.(*http.Transport)
+	return t
+}
+
+// wrapDial makes the client's *http.Transport pass every connection it dials
+// through fn. It reports false, doing nothing, if a RoundTripper other than an
+// *http.Transport has been provided.
+//
+// The wrappers are installed by New once every option has been applied, on
+// top of whatever dial function the options left on the transport, so options
+// that replace the dial function, such as WithLocalAddr, can come in any
+// order.
+func (c *Client) wrapDial(fn func(conn net.Conn) net.Conn) bool {
+	if c.transport() == nil {
+		return false
+	}
+	c.dialWrappers = append(c.dialWrappers, fn)
+	return true
+}
+
+// installDialWrappers wraps the dial function of the client's *http.Transport
+// in the functions passed to wrapDial, the first innermost.
+func (c *Client) installDialWrappers() {
+	if len(c.dialWrappers) == 0 {
+		return
+	}
+	t := c.transport()
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	wrappers := c.dialWrappers
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		for _, fn := range wrappers {
+			conn = fn(conn)
+		}
+		return conn, nil
+	}
+}
+
 // New returns a client, optionally modified by passing it through the given
 // Option functions.
 func New(opts ...Option) *Client {
@@ -63,11 +251,17 @@ func New(opts ...Option) *Client {
 		Client: &http.Client{
 			Timeout: DefaultTimeout,
 		},
+		clock: clock.Real,
 	}
 
 	for _, opt := range opts {
 		c = opt(c)
 	}
+	c.installDialWrappers()
+	c.base = c.Transport
+	c.Transport = c.chain(c.Transport)
+	c.checkRedirect = c.CheckRedirect != nil
+	c.checkAuthRedirect()
 
 	return c
 }
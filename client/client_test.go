@@ -1,6 +1,13 @@
 package client_test
 
 import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,3 +26,200 @@ func TestClientTimeout(t *testing.T) {
 		t.Errorf("expected timeout %s, got %s", to, c2.Timeout)
 	}
 }
+
+func TestClientDisableHTTP2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	base := ts.Client().Transport.(*http.Transport)
+
+	c := client.New(client.WithTransport(base.Clone()))
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Proto != "HTTP/2.0" {
+		t.Fatalf("expected protocol HTTP/2.0 without option, got %s", resp.Proto)
+	}
+
+	c2 := client.New(client.WithTransport(base.Clone()), client.WithDisableHTTP2())
+	resp, err = c2.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Proto != "HTTP/1.1" {
+		t.Errorf("expected protocol HTTP/1.1, got %s", resp.Proto)
+	}
+}
+
+// freezingProxy forwards TCP connections to a target until freeze is called,
+// after which nothing more is forwarded on the connections open so far, as
+// when a middlebox silently drops them. closed receives a value whenever the
+// client closes one of its connections.
+type freezingProxy struct {
+	addr   string
+	gen    atomic.Int64
+	closed chan struct{}
+}
+
+func startFreezingProxy(t *testing.T, target string) *freezingProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &freezingProxy{addr: ln.Addr().String(), closed: make(chan struct{}, 10)}
+
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	t.Cleanup(func() {
+		ln.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range conns {
+			c.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", target)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			mu.Lock()
+			conns = append(conns, conn, upstream)
+			mu.Unlock()
+
+			gen := p.gen.Load()
+			forward := func(dst, src net.Conn) {
+				buf := make([]byte, 32*1024)
+				for {
+					n, err := src.Read(buf)
+					if err != nil {
+						return
+					}
+					if p.gen.Load() != gen {
+						continue
+					}
+					if _, err := dst.Write(buf[:n]); err != nil {
+						return
+					}
+				}
+			}
+			go forward(conn, upstream)
+			go func() {
+				forward(upstream, conn)
+				p.closed <- struct{}{}
+			}()
+		}
+	}()
+	return p
+}
+
+func (p *freezingProxy) freeze() { p.gen.Add(1) }
+
+func TestClientHTTP2HealthCheck(t *testing.T) {
+	var conns atomic.Int64
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.EnableHTTP2 = true
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+	proxy := startFreezingProxy(t, ts.Listener.Addr().String())
+	url := "https://" + proxy.addr
+
+	base := ts.Client().Transport.(*http.Transport)
+	c := client.New(client.WithTransport(base.Clone()), client.WithHTTP2HealthCheck(50*time.Millisecond, 50*time.Millisecond))
+
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Proto != "HTTP/2.0" {
+		t.Fatalf("expected protocol HTTP/2.0, got %s", resp.Proto)
+	}
+
+	proxy.freeze()
+	select {
+	case <-proxy.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead connection was not closed")
+	}
+
+	resp, err = c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := conns.Load(); n != 2 {
+		t.Errorf("expected a new connection after the dead one was closed, got %d connections", n)
+	}
+}
+
+func TestClientMaxConnsPerHost(t *testing.T) {
+	var conns atomic.Int64
+	entered, release := make(chan struct{}, 3), make(chan struct{})
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	c := client.New(client.WithMaxConnsPerHost(1))
+	tr := c.Transport.(*http.Transport)
+	if tr.MaxConnsPerHost != 1 || tr.MaxIdleConnsPerHost != 1 {
+		t.Fatalf("expected 1 conn and 1 idle conn per host, got %d and %d", tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(ts.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	<-entered
+
+	// A queued request gives up when its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error while queued, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+	if got := conns.Load(); got != 1 {
+		t.Errorf("expected 1 connection, got %d", got)
+	}
+}
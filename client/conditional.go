@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetConditional fetches url, sending etag in an If-None-Match header so the
+// server can skip sending a resource that has not changed. It reports whether
+// the resource changed: false if the server responded with 304 Not Modified,
+// and true for a 2xx response. Any other status returns a *StatusError. An
+// empty etag sends an unconditional request, which always reports a change.
+//
+// The resource's current ETag is in the returned response's ETag header, ready
+// to pass to the next call when polling. If a 304 response omits the header,
+// it is set to etag, so the header can always be chained. The caller must
+// close the response body, which is empty for a 304.
+func (c *Client) GetConditional(ctx context.Context, url, etag string) (*http.Response, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		if resp.Header.Get("ETag") == "" && etag != "" {
+			resp.Header.Set("ETag", etag)
+		}
+		return resp, false, nil
+	}
+	if err := checkStatus(resp); err != nil {
+		drainAndClose(resp.Body)
+		return nil, false, err
+	}
+	return resp, true, nil
+}
@@ -0,0 +1,91 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestGetConditional(t *testing.T) {
+	etag := `"v1"`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("widgets"))
+	}))
+	defer ts.Close()
+
+	c := client.New()
+
+	// A first poll has no ETag, so the resource is always sent.
+	resp, changed, err := c.GetConditional(context.Background(), ts.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !changed {
+		t.Error("expected the first poll to report a change")
+	}
+	if string(body) != "widgets" {
+		t.Errorf("expected body %q, got %q", "widgets", body)
+	}
+	if got := resp.Header.Get("ETag"); got != etag {
+		t.Fatalf("expected ETag %s, got %q", etag, got)
+	}
+
+	// Polling with the current ETag gets a 304, and the ETag carries over.
+	resp, changed, err = c.GetConditional(context.Background(), ts.URL, resp.Header.Get("ETag"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if changed {
+		t.Error("expected an unchanged resource")
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, resp.StatusCode)
+	}
+	if got := resp.Header.Get("ETag"); got != etag {
+		t.Errorf("expected ETag %s, got %q", etag, got)
+	}
+
+	// Once the resource changes, the new ETag is returned.
+	old := etag
+	etag = `"v2"`
+	resp, changed, err = c.GetConditional(context.Background(), ts.URL, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !changed {
+		t.Error("expected a changed resource")
+	}
+	if got := resp.Header.Get("ETag"); got != etag {
+		t.Errorf("expected ETag %s, got %q", etag, got)
+	}
+}
+
+func TestGetConditionalStatusError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer ts.Close()
+
+	_, _, err := client.New().GetConditional(context.Background(), ts.URL, `"v1"`)
+	var serr *client.StatusError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected a *StatusError, got %v", err)
+	}
+	if serr.StatusCode != http.StatusGone {
+		t.Errorf("expected status %d, got %d", http.StatusGone, serr.StatusCode)
+	}
+}
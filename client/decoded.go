@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MaxDecodedBytes is the largest body, after decompression, that GetDecoded
+// reads before giving up with ErrResponseTooLarge.
+const MaxDecodedBytes = 10 << 20
+
+// ErrResponseTooLarge is returned by GetDecoded when the response body is
+// larger than MaxDecodedBytes.
+var ErrResponseTooLarge = errors.New("client: response too large")
+
+// XMLNode is a generic XML element, as returned by GetDecoded for XML
+// responses.
+type XMLNode struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Children []XMLNode
+
+	// Text is the element's character data, with surrounding whitespace
+	// removed.
+	Text string
+}
+
+// GetDecoded fetches url and decodes the response body according to its
+// Content-Type, for tools that have no concrete type to decode into. If the
+// response status is not 2xx, a *StatusError is returned.
+//
+// JSON bodies, including media types with a "+json" suffix, are decoded into
+// the generic values produced by encoding/json, such as map[string]any and
+// []any. XML bodies, including "+xml" types, are decoded into an XMLNode for
+// the root element. Any other body is returned as a []byte.
+//
+// A body with a gzip or deflate Content-Encoding is decompressed first. Other
+// encodings are an error. Bodies larger than MaxDecodedBytes once
+// decompressed return ErrResponseTooLarge.
+func (c *Client) GetDecoded(ctx context.Context, url string) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	body, err := decompress(resp)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, MaxDecodedBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDecodedBytes {
+		return nil, ErrResponseTooLarge
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return decodeXML(data)
+	}
+	return data, nil
+}
+
+// decompress returns a reader over resp's body with its Content-Encoding
+// removed.
+func decompress(resp *http.Response) (io.Reader, error) {
+	switch enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		return zlib.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("client: unsupported content encoding %q", enc)
+	}
+}
+
+// decodeXML decodes the root element of data.
+func decodeXML(data []byte) (XMLNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*XMLNode
+	var root XMLNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return root, errors.New("client: XML document has no root element")
+		}
+		if err != nil {
+			return XMLNode{}, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			node := XMLNode{Name: tok.Name, Attrs: tok.Attr}
+			if len(stack) == 0 {
+				root = node
+				stack = append(stack, &root)
+				continue
+			}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, &parent.Children[len(parent.Children)-1])
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(tok)
+			}
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			node.Text = strings.TrimSpace(node.Text)
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return root, nil
+			}
+		}
+	}
+}
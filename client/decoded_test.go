@@ -0,0 +1,76 @@
+package client_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestGetDecoded(t *testing.T) {
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write([]byte(`{"id": 42, "tags": ["a", "b"]}`))
+	zw.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped.Bytes())
+	})
+	mux.HandleFunc("/xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write([]byte(`<feed lang="en"><title> Widgets </title><entry/></feed>`))
+	})
+	mux.HandleFunc("/raw", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte{0, 1, 2})
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c := client.New()
+	get := func(path string) any {
+		t.Helper()
+		v, err := c.GetDecoded(context.Background(), ts.URL+path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return v
+	}
+
+	want := map[string]any{"id": 42.0, "tags": []any{"a", "b"}}
+	if got := get("/json"); !reflect.DeepEqual(got, want) {
+		t.Errorf("json: expected %v, got %v", want, got)
+	}
+
+	node, ok := get("/xml").(client.XMLNode)
+	if !ok {
+		t.Fatalf("xml: expected an XMLNode")
+	}
+	if node.Name.Local != "feed" || len(node.Attrs) != 1 || node.Attrs[0].Value != "en" {
+		t.Errorf("xml: unexpected root %+v", node)
+	}
+	if len(node.Children) != 2 || node.Children[0].Text != "Widgets" || node.Children[1].Name.Local != "entry" {
+		t.Errorf("xml: unexpected children %+v", node.Children)
+	}
+
+	if got := get("/raw"); !bytes.Equal(got.([]byte), []byte{0, 1, 2}) {
+		t.Errorf("raw: expected the raw bytes, got %v", got)
+	}
+
+	var serr *client.StatusError
+	if _, err := c.GetDecoded(context.Background(), ts.URL+"/missing"); !errors.As(err, &serr) {
+		t.Errorf("expected a *StatusError, got %v", err)
+	}
+}
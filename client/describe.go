@@ -0,0 +1,102 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/haleyrc/http/internal/describe"
+)
+
+// Describe returns a human-readable summary of the client's effective
+// configuration after all options were applied, such as its timeouts,
+// transport limits and installed middleware, for including in bug reports.
+// Credentials, such as a proxy password, are redacted.
+func (c *Client) Describe() string {
+	return c.describe().String()
+}
+
+// DescribeJSON returns the same summary as Describe as a JSON object.
+func (c *Client) DescribeJSON() ([]byte, error) {
+	return json.Marshal(c.describe())
+}
+
+func (c *Client) describe() describe.Fields {
+	var fs describe.Fields
+	fs.Add("timeout", c.Timeout)
+	fs.Add("cookie_jar", c.Jar != nil)
+	fs.Add("check_redirect", c.checkRedirect)
+	fs.Add("auth_redirect_policy", c.authRedirect.String())
+
+	t := c.baseTransport()
+	switch {
+	case t == nil:
+		fs.Add("transport", "custom")
+	default:
+		fs.Add("transport", "http.Transport")
+		fs.Add("max_conns_per_host", t.MaxConnsPerHost)
+		fs.Add("max_idle_conns", t.MaxIdleConns)
+		fs.Add("max_idle_conns_per_host", t.MaxIdleConnsPerHost)
+		fs.Add("idle_conn_timeout", t.IdleConnTimeout)
+		fs.Add("tls_handshake_timeout", t.TLSHandshakeTimeout)
+		fs.Add("response_header_timeout", t.ResponseHeaderTimeout)
+		fs.Add("disable_keep_alives", t.DisableKeepAlives)
+		fs.Add("http2_disabled", t.TLSNextProto != nil && len(t.TLSNextProto) == 0)
+		if t.HTTP2 != nil && t.HTTP2.SendPingTimeout > 0 {
+			fs.Add("http2_health_check", fmt.Sprintf("read_idle=%s ping_timeout=%s", t.HTTP2.SendPingTimeout, t.HTTP2.PingTimeout))
+		}
+		if t.TLSClientConfig != nil {
+			fs.Add("tls_min_version", describe.TLSVersion(t.TLSClientConfig.MinVersion))
+			fs.Add("tls_insecure_skip_verify", t.TLSClientConfig.InsecureSkipVerify)
+		}
+		proxy := c.proxy
+		if proxy == "" {
+			proxy = "none"
+			if t.Proxy != nil {
+				proxy = "environment"
+			}
+		}
+		fs.Add("proxy", proxy)
+	}
+
+	if c.retry != nil {
+		fs.Add("retry", fmt.Sprintf("max_attempts=%d backoff=%s-%s", c.retry.MaxAttempts, c.retry.MinBackoff, c.retry.MaxBackoff))
+	}
+	if c.retryBudget != nil {
+		fs.Add("retry_budget", c.retryBudget.ratio)
+	}
+	fs.Add("middleware", c.middlewareNames())
+	return fs
+}
+
+// middlewareNames lists the client's middleware from outermost to innermost.
+func (c *Client) middlewareNames() []string {
+	var names []string
+	for _, l := range c.outer {
+		names = append(names, l.name)
+	}
+	for _, l := range c.middleware {
+		names = append(names, l.name)
+	}
+	if c.retry != nil {
+		names = append(names, "retry")
+	}
+	for _, l := range c.attempt {
+		names = append(names, l.name)
+	}
+	if c.forceClose {
+		names = append(names, "force_close")
+	}
+	return names
+}
+
+// baseTransport returns the *http.Transport beneath the client's middleware,
+// or nil if it uses some other RoundTripper. Unlike transport, it never
+// installs a transport.
+func (c *Client) baseTransport() *http.Transport {
+	if c.base == nil {
+		return http.DefaultTransport.(*http.Transport)
+	}
+	t, _ := c.base.(*http.Transport)
+	return t
+}
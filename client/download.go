@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrIncompleteDownload is returned by Download when the response body ends
+// before the number of bytes given by its Content-Length.
+var ErrIncompleteDownload = errors.New("client: incomplete download")
+
+// ErrRangeMismatch is returned by Download when the server answers a resumed
+// download with 206 Partial Content starting somewhere other than the end of
+// the partial download.
+var ErrRangeMismatch = errors.New("client: partial content does not match the requested range")
+
+// DownloadOptions configures Download.
+type DownloadOptions struct {
+	// Progress, if non-nil, is called after each chunk is written with the
+	// number of bytes downloaded so far, including any resumed partial
+	// download, and the total size, or -1 if the server did not report it.
+	Progress func(done, total int64)
+
+	// Resume keeps the partial download if Download fails, and continues it
+	// on the next call for the same dest if the server supports range
+	// requests. Without Resume, any partial download is discarded.
+	Resume bool
+
+	// Checksum, if set with WithExpectedChecksum, is verified as the body is
+	// written. If the downloaded file does not match, Download returns
+	// ErrChecksumMismatch and discards it, even if Resume is set.
+	Checksum ExpectedChecksum
+}
+
+// partSuffix is appended to dest to name the file a download is written to
+// before it is complete.
+const partSuffix = ".part"
+
+// Download fetches url and streams the body to the file dest. The body is
+// written to dest+".part" and only renamed to dest once it is complete, so
+// dest never holds a partial download. If the response status is not 2xx, a
+// *StatusError is returned.
+//
+// If opts.Resume is set and a partial download exists, the request asks for
+// the remaining bytes with a Range header. A server that answers with 206
+// Partial Content is resumed from where it left off; any other 2xx response
+// restarts the download from the beginning. A 206 response for any other
+// range returns ErrRangeMismatch, leaving the partial download untouched.
+//
+// The client's timeout applies to the whole download, including reading the
+// body, so clients downloading large files should use a long or zero timeout
+// and bound the download with ctx instead.
+func (c *Client) Download(ctx context.Context, url, dest string, opts DownloadOptions) (err error) {
+	part := dest + partSuffix
+
+	var sum hash.Hash
+	if !opts.Checksum.isZero() {
+		if sum, err = opts.Checksum.newHash(); err != nil {
+			return err
+		}
+	}
+
+	var offset int64
+	if opts.Resume {
+		if info, err := os.Stat(part); err == nil {
+			offset = info.Size()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resp.StatusCode == http.StatusPartialContent {
+		if start := rangeStart(resp); start != offset {
+			return fmt.Errorf("%w: asked for byte %d, got %d", ErrRangeMismatch, offset, start)
+		}
+		if offset > 0 {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+	} else {
+		offset = 0
+	}
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	discard := !opts.Resume
+	defer func() {
+		if err == nil {
+			return
+		}
+		f.Close()
+		if discard {
+			os.Remove(part)
+		}
+	}()
+
+	if sum != nil && offset > 0 {
+		if err := hashFile(sum, part); err != nil {
+			return err
+		}
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	w := io.Writer(f)
+	if sum != nil {
+		w = io.MultiWriter(f, sum)
+	}
+	if opts.Progress != nil {
+		w = &progressWriter{w: w, done: offset, total: total, fn: opts.Progress}
+	}
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return fmt.Errorf("%w: got %d of %d bytes", ErrIncompleteDownload, n, resp.ContentLength)
+	}
+	if sum != nil {
+		if err := opts.Checksum.verify(sum); err != nil {
+			discard = true
+			return err
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(part, dest)
+}
+
+// hashFile writes the contents of the named file to h.
+func hashFile(h hash.Hash, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// rangeStart returns the first byte position of a 206 response's
+// Content-Range header, or -1 if it cannot be parsed.
+func rangeStart(resp *http.Response) int64 {
+	rest, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// progressWriter reports the running total of bytes written through it.
+type progressWriter struct {
+	w     io.Writer
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.done += int64(n)
+	pw.fn(pw.done, pw.total)
+	return n, err
+}
@@ -0,0 +1,145 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+var artifact = []byte(strings.Repeat("0123456789", 10000))
+
+// artifactServer serves artifact with range support, recording the Range
+// header of the last request.
+func artifactServer(t *testing.T, gotRange *string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotRange = r.Header.Get("Range")
+		http.ServeContent(w, r, "artifact.bin", time.Time{}, bytes.NewReader(artifact))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestDownload(t *testing.T) {
+	var gotRange string
+	ts := artifactServer(t, &gotRange)
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+
+	var done, total int64
+	err := client.New().Download(context.Background(), ts.URL, dest, client.DownloadOptions{
+		Progress: func(d, t int64) { done, total = d, t },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, artifact) {
+		t.Errorf("expected %d bytes of artifact, got %d bytes", len(artifact), len(got))
+	}
+	if done != int64(len(artifact)) || total != int64(len(artifact)) {
+		t.Errorf("expected final progress %d/%d, got %d/%d", len(artifact), len(artifact), done, total)
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("expected the partial file to be gone, got %v", err)
+	}
+	if gotRange != "" {
+		t.Errorf("expected no Range header, got %q", gotRange)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	var gotRange string
+	ts := artifactServer(t, &gotRange)
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+
+	const have = 4096
+	if err := os.WriteFile(dest+".part", artifact[:have], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var first int64 = -1
+	err := client.New().Download(context.Background(), ts.URL, dest, client.DownloadOptions{
+		Resume: true,
+		Progress: func(d, t int64) {
+			if first < 0 {
+				first = d
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gotRange != "bytes=4096-" {
+		t.Errorf("expected Range bytes=4096-, got %q", gotRange)
+	}
+	if first <= have {
+		t.Errorf("expected progress to start past %d bytes, got %d", have, first)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, artifact) {
+		t.Errorf("expected the resumed file to match the artifact")
+	}
+}
+
+func TestDownloadRangeMismatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 100-199/1000")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(artifact[100:200])
+	}))
+	defer ts.Close()
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+
+	const have = 4096
+	if err := os.WriteFile(dest+".part", artifact[:have], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.New().Download(context.Background(), ts.URL, dest, client.DownloadOptions{Resume: true})
+	if !errors.Is(err, client.ErrRangeMismatch) {
+		t.Fatalf("expected ErrRangeMismatch, got %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("expected dest not to exist, got %v", err)
+	}
+	if got, _ := os.ReadFile(dest + ".part"); !bytes.Equal(got, artifact[:have]) {
+		t.Errorf("expected the partial download to be left untouched, got %d bytes", len(got))
+	}
+}
+
+func TestDownloadIncomplete(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("short"))
+	}))
+	defer ts.Close()
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+
+	err := client.New().Download(context.Background(), ts.URL, dest, client.DownloadOptions{Resume: true})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("expected dest not to exist, got %v", err)
+	}
+	if got, _ := os.ReadFile(dest + ".part"); string(got) != "short" {
+		t.Errorf("expected the partial download to be kept for resuming, got %q", got)
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxErrorBody is the maximum number of bytes of a response body retained in
+// a StatusError.
+const maxErrorBody = 4 << 10
+
+// StatusError is returned by the client's helper methods when the server
+// responds with a non-2xx status code.
+type StatusError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Status is the status line of the response, e.g. "404 Not Found".
+	Status string
+
+	// Body holds up to the first 4KB of the response body, which often
+	// contains a more detailed error message from the server.
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("client: unexpected status %s", e.Status)
+	}
+	return fmt.Sprintf("client: unexpected status %s: %s", e.Status, e.Body)
+}
+
+// checkStatus returns a *StatusError if resp does not have a 2xx status code.
+// The body is read, but not closed, in that case.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       body,
+	}
+}
+
+// maxDrain is the most drainAndClose reads from a body before giving up on
+// reusing its connection.
+const maxDrain = 64 << 10
+
+// drainAndClose discards up to maxDrain bytes of any remaining body so the
+// underlying connection can be reused, then closes it. A larger or endless
+// body is not read to the end; closing it closes the connection instead.
+func drainAndClose(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDrain)
+	body.Close()
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Fetch sends req with ctx and calls fn with the response, then drains and
+// closes the response body once fn returns, whether it returned an error,
+// returned early without reading the body, or panicked. Draining the body lets
+// the connection be reused, so an early return cannot leak a connection from
+// the pool. Only the first 64KB of what is left is drained; the connection of
+// a larger or streaming body is closed rather than read to the end. It returns
+// the error from sending the request, or else fn's error.
+//
+// fn must not keep the body after returning. The response status is not
+// checked; fn sees every response, including errors.
+func (c *Client) Fetch(ctx context.Context, req *http.Request, fn func(resp *http.Response) error) error {
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+	return fn(resp)
+}
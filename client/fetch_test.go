@@ -0,0 +1,126 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+// closeTracker records whether the response body it wraps was closed.
+type closeTracker struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (b *closeTracker) Close() error {
+	b.closed = true
+	return b.ReadCloser.Close()
+}
+
+func TestFetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("a", 8<<10))
+	}))
+	defer ts.Close()
+
+	var bodies []*closeTracker
+	track := func(next http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err == nil {
+				body := &closeTracker{ReadCloser: resp.Body}
+				bodies = append(bodies, body)
+				resp.Body = body
+			}
+			return resp, err
+		})
+	}
+	c := client.New(client.WithMiddleware(track))
+
+	var reused []bool
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = append(reused, info.Reused) }}
+	ctx := httptrace.WithClientTrace(context.Background(), trace)
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+
+	errEarly := errors.New("returned early")
+	err := c.Fetch(ctx, req, func(resp *http.Response) error {
+		return errEarly
+	})
+	if !errors.Is(err, errEarly) {
+		t.Errorf("expected fn's error, got %v", err)
+	}
+
+	func() {
+		defer func() { recover() }()
+		c.Fetch(ctx, req, func(resp *http.Response) error {
+			panic("boom")
+		})
+	}()
+
+	err = c.Fetch(ctx, req, func(resp *http.Response) error {
+		_, err := io.ReadAll(resp.Body)
+		return err
+	})
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	for i, body := range bodies {
+		if !body.closed {
+			t.Errorf("request %d: expected the body to be closed after fn returned", i+1)
+		}
+	}
+	if len(reused) != 3 || reused[0] || !reused[1] || !reused[2] {
+		t.Errorf("expected the connection to be reused after every request, got %v", reused)
+	}
+}
+
+func TestFetchRequestError(t *testing.T) {
+	c := client.New()
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:0", nil)
+	called := false
+	err := c.Fetch(context.Background(), req, func(resp *http.Response) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("expected the request error without calling fn, got %v (called %t)", err, called)
+	}
+}
+
+func TestFetchEndlessBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := []byte(strings.Repeat("a", 4<<10))
+		for r.Context().Err() == nil {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	c := client.New(client.WithTimeout(0))
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Fetch(context.Background(), req, func(resp *http.Response) error {
+			return nil
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Fetch to return without reading the whole body")
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PostForm sends values to url as an application/x-www-form-urlencoded POST.
+// If the response status is not 2xx, a *StatusError is returned.
+//
+// If out is non-nil, the response body is decoded into it as JSON. Otherwise
+// the body is discarded. Either way, the body is fully drained so the
+// connection can be reused.
+func (c *Client) PostForm(ctx context.Context, url string, values url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,50 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestPostForm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("expected form content type, got %q", ct)
+		}
+		switch r.PostFormValue("name") {
+		case "widget":
+			w.Write([]byte(`{"id": 42}`))
+		default:
+			http.Error(w, "bad name", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	c := client.New()
+
+	var out struct{ ID int }
+	if err := c.PostForm(context.Background(), ts.URL, url.Values{"name": {"widget"}}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != 42 {
+		t.Errorf("expected id 42, got %d", out.ID)
+	}
+
+	if err := c.PostForm(context.Background(), ts.URL, url.Values{"name": {"widget"}}, nil); err != nil {
+		t.Errorf("expected no error with nil out, got %v", err)
+	}
+
+	err := c.PostForm(context.Background(), ts.URL, url.Values{"name": {"gadget"}}, nil)
+	var serr *client.StatusError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected a *StatusError, got %v", err)
+	}
+	if serr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, serr.StatusCode)
+	}
+}
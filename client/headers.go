@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WithContextHeader returns an Option that, for every request, looks up key in
+// the request's context and, if the value is a non-empty string, sends it in
+// the named header. If the value is absent or empty, the header is omitted.
+//
+// This is useful for propagating request-scoped values such as a tenant or
+// request ID to downstream services.
+func WithContextHeader(header string, key any) Option {
+	return withNamedMiddleware("context_header("+http.CanonicalHeaderKey(header)+")", func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if v, ok := req.Context().Value(key).(string); ok && v != "" {
+				req = cloneRequest(req)
+				req.Header.Set(header, v)
+			}
+			return next.RoundTrip(req)
+		})
+	})
+}
+
+// WithDefaultAccept returns an Option that sends an Accept header of mediaType,
+// such as "application/json", on every request that does not already have
+// one. A request's own Accept header is never overridden, whether the caller
+// set it or middleware added earlier did.
+func WithDefaultAccept(mediaType string) Option {
+	return withNamedMiddleware("default_accept", func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Accept") == "" {
+				req = cloneRequest(req)
+				req.Header.Set("Accept", mediaType)
+			}
+			return next.RoundTrip(req)
+		})
+	})
+}
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx that makes a request sent with it by a
+// Client configured with WithAcceptLanguage ask for the language tag, such as
+// "fr-CA", instead of the client's default languages.
+func WithLocale(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, localeKey{}, tag)
+}
+
+// WithAcceptLanguage returns an Option that sends an Accept-Language header
+// listing tags, in order of preference, on every request that does not
+// already have one. The first tag is preferred, and each later one is given a
+// q-value 0.1 lower than the one before, down to a minimum of 0.1, so
+// WithAcceptLanguage("en-US", "en", "fr") sends "en-US, en;q=0.9, fr;q=0.8".
+//
+// A locale set on the request's context with WithLocale replaces tags for
+// that request. With no tags, only requests with a locale get the header.
+func WithAcceptLanguage(tags ...string) Option {
+	header := acceptLanguage(tags)
+	return withNamedMiddleware("accept_language", func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Accept-Language") != "" {
+				return next.RoundTrip(req)
+			}
+			value := header
+			if tag, ok := req.Context().Value(localeKey{}).(string); ok && tag != "" {
+				value = tag
+			}
+			if value != "" {
+				req = cloneRequest(req)
+				req.Header.Set("Accept-Language", value)
+			}
+			return next.RoundTrip(req)
+		})
+	})
+}
+
+// acceptLanguage formats tags as an Accept-Language header value with q-values
+// decreasing in tenths.
+func acceptLanguage(tags []string) string {
+	var b strings.Builder
+	for i, tag := range tags {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(tag)
+		if i > 0 {
+			fmt.Fprintf(&b, ";q=0.%d", max(10-i, 1))
+		}
+	}
+	return b.String()
+}
+
+// cloneRequest returns a shallow copy of req with a deep copy of its headers,
+// so middleware can modify the headers without mutating the caller's request
+// as the RoundTripper contract forbids.
+func cloneRequest(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = req.Header.Clone()
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	return r
+}
@@ -0,0 +1,137 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+// echoHeader returns a test server that responds with the value of the named
+// request header in the X-Echo response header.
+func echoHeader(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Echo", r.Header.Get(name))
+		if _, ok := r.Header[http.CanonicalHeaderKey(name)]; !ok {
+			w.Header().Set("X-Echo-Absent", "true")
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+type tenantKey struct{}
+
+func TestContextHeader(t *testing.T) {
+	ts := echoHeader(t, "X-Tenant")
+	c := client.New(client.WithContextHeader("X-Tenant", tenantKey{}))
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Echo"); got != "acme" {
+		t.Errorf("expected header %q, got %q", "acme", got)
+	}
+	if req.Header.Get("X-Tenant") != "" {
+		t.Error("expected the caller's request to be unmodified")
+	}
+
+	resp, err = c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Echo-Absent") != "true" {
+		t.Error("expected header to be omitted")
+	}
+}
+
+func TestDefaultAccept(t *testing.T) {
+	ts := echoHeader(t, "Accept")
+	c := client.New(client.WithDefaultAccept("application/json"))
+
+	for _, tt := range []struct {
+		name, accept, want string
+	}{
+		{"absent", "", "application/json"},
+		{"present", "application/xml", "application/xml"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", ts.URL, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("X-Echo"); got != tt.want {
+				t.Errorf("expected Accept %q, got %q", tt.want, got)
+			}
+			if tt.accept == "" && req.Header.Get("Accept") != "" {
+				t.Error("expected the caller's request to be left unmodified")
+			}
+		})
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	ts := echoHeader(t, "Accept-Language")
+
+	for _, tt := range []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{"single", []string{"en-US"}, "en-US"},
+		{"several", []string{"en-US", "en", "fr"}, "en-US, en;q=0.9, fr;q=0.8"},
+		{"many", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"}, "a, b;q=0.9, c;q=0.8, d;q=0.7, e;q=0.6, f;q=0.5, g;q=0.4, h;q=0.3, i;q=0.2, j;q=0.1, k;q=0.1, l;q=0.1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.New(client.WithAcceptLanguage(tt.tags...)).Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("X-Echo"); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAcceptLanguageLocale(t *testing.T) {
+	ts := echoHeader(t, "Accept-Language")
+	c := client.New(client.WithAcceptLanguage("en-US", "en"))
+
+	ctx := client.WithLocale(context.Background(), "fr-CA")
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Echo"); got != "fr-CA" {
+		t.Errorf("expected the context locale, got %q", got)
+	}
+
+	// An explicit header wins over both.
+	req, _ = http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	req.Header.Set("Accept-Language", "de")
+	resp, err = c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Echo"); got != "de" {
+		t.Errorf("expected the explicit header, got %q", got)
+	}
+}
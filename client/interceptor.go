@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithRequestInterceptor returns an Option that calls fn with every outbound
+// request immediately before it is sent, allowing last-minute changes such as
+// signing the request or adding a header computed from its body. If fn
+// returns an error, the request is not sent and the error is returned from
+// the round trip.
+//
+// fn receives a copy of the request with its own headers, so it may modify
+// them freely without affecting the caller's request. If it reads the body, it
+// must replace it, for example using GetBody.
+//
+// Interceptors run beneath all middleware added with WithMiddleware, so they
+// see each request exactly as it will be sent, and beneath WithRetry, so they
+// run again for every attempt. Multiple interceptors run in the order they
+// were added.
+func WithRequestInterceptor(fn func(ctx context.Context, req *http.Request) error) Option {
+	return func(c *Client) *Client {
+		c.attempt = append(c.attempt, layer{name: "request_interceptor", mw: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req = cloneRequest(req)
+				if err := fn(req.Context(), req); err != nil {
+					if req.Body != nil {
+						req.Body.Close()
+					}
+					return nil, err
+				}
+				return next.RoundTrip(req)
+			})
+		}})
+		return c
+	}
+}
+
+// WithResponseInterceptor returns an Option that calls fn with every response
+// before it is returned to the caller, allowing its status and headers to be
+// inspected. If fn returns an error, the response body is closed and the error
+// is returned instead, which can be used to treat an API's error envelope as a
+// failed request.
+//
+// The body is not read before fn is called. fn may read it, but must then
+// replace resp.Body with a reader over what it read if the caller is to see
+// the body too.
+//
+// Interceptors run above all middleware added with WithMiddleware and above
+// WithRetry, so they see only the final response of each round trip. Multiple
+// interceptors run in the order they were added, so the first sees each
+// response first.
+func WithResponseInterceptor(fn func(ctx context.Context, resp *http.Response) error) Option {
+	return func(c *Client) *Client {
+		// The outermost layer sees the response last, so interceptors are
+		// prepended to run in the order they were added.
+		c.outer = append([]layer{{name: "response_interceptor", mw: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				resp, err := next.RoundTrip(req)
+				if err != nil {
+					return resp, err
+				}
+				if err := fn(req.Context(), resp); err != nil {
+					resp.Body.Close()
+					return nil, err
+				}
+				return resp, nil
+			})
+		}}}, c.outer...)
+		return c
+	}
+}
@@ -0,0 +1,154 @@
+package client_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+// sign sets X-Signature to the SHA-256 of the request body.
+func sign(ctx context.Context, req *http.Request) error {
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	req.Header.Set("X-Signature", hex.EncodeToString(sum[:]))
+	return nil
+}
+
+func TestRequestInterceptor(t *testing.T) {
+	ts := echoHeader(t, "X-Signature")
+	c := client.New(client.WithRequestInterceptor(sign))
+
+	req, _ := http.NewRequest("POST", ts.URL, strings.NewReader("payload"))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	sum := sha256.Sum256([]byte("payload"))
+	if got, want := resp.Header.Get("X-Echo"), hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("expected signature %q, got %q", want, got)
+	}
+	if got := req.Header.Get("X-Signature"); got != "" {
+		t.Errorf("expected the caller's request to be unchanged, got signature %q", got)
+	}
+}
+
+func TestRequestInterceptorError(t *testing.T) {
+	ts := echoHeader(t, "X-Signature")
+	errNoKey := errors.New("no signing key")
+	c := client.New(client.WithRequestInterceptor(func(ctx context.Context, req *http.Request) error {
+		return errNoKey
+	}))
+
+	if _, err := c.Get(ts.URL); !errors.Is(err, errNoKey) {
+		t.Errorf("expected error %v, got %v", errNoKey, err)
+	}
+}
+
+func TestRequestInterceptorOrdering(t *testing.T) {
+	ts, _ := flaky(t, 1)
+
+	var seen []string
+	attempts := 0
+	c := client.New(
+		client.WithRequestInterceptor(func(ctx context.Context, req *http.Request) error {
+			attempts++
+			seen = append(seen, req.Header.Get("X-Added"))
+			return nil
+		}),
+		client.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req = req.Clone(req.Context())
+				req.Header.Set("X-Added", "yes")
+				return next.RoundTrip(req)
+			})
+		}),
+		client.WithRetry(fastRetry),
+	)
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if attempts != 2 {
+		t.Errorf("expected the interceptor to run for both attempts, ran %d times", attempts)
+	}
+	for _, v := range seen {
+		if v != "yes" {
+			t.Errorf("expected the interceptor to see middleware headers, got %q", v)
+		}
+	}
+}
+
+func TestResponseInterceptor(t *testing.T) {
+	ts := echoHeader(t, "X-Tenant")
+
+	errUnauthorized := errors.New("unauthorized")
+	var order []string
+	c := client.New(
+		client.WithResponseInterceptor(func(ctx context.Context, resp *http.Response) error {
+			order = append(order, "first")
+			return nil
+		}),
+		client.WithResponseInterceptor(func(ctx context.Context, resp *http.Response) error {
+			order = append(order, "second")
+			if resp.Header.Get("X-Echo-Absent") == "true" {
+				return errUnauthorized
+			}
+			return nil
+		}),
+	)
+
+	if _, err := c.Get(ts.URL); !errors.Is(err, errUnauthorized) {
+		t.Errorf("expected error %v, got %v", errUnauthorized, err)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("expected interceptors to run in order, got %v", order)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestResponseInterceptorAboveRetry(t *testing.T) {
+	ts, _ := flaky(t, 1)
+
+	var statuses []int
+	c := client.New(
+		client.WithRetry(fastRetry),
+		client.WithResponseInterceptor(func(ctx context.Context, resp *http.Response) error {
+			statuses = append(statuses, resp.StatusCode)
+			return nil
+		}),
+	)
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(statuses) != 1 || statuses[0] != http.StatusOK {
+		t.Errorf("expected the interceptor to see only the final 200, got %v", statuses)
+	}
+}
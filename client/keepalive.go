@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithDisableKeepAlives returns an Option that makes the client use a new
+// connection for every request and close it afterwards, for upstreams that
+// misbehave with persistent connections. This costs a TCP handshake, and a
+// TLS handshake for HTTPS, on every request, which adds latency and load on
+// both ends; prefer WithForceClose for individual requests where possible.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithDisableKeepAlives() Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil {
+			t.DisableKeepAlives = true
+		}
+		return c
+	}
+}
+
+type forceCloseKey struct{}
+
+// WithForceClose returns a copy of ctx that makes a request sent with it by a
+// Client created with WithForceCloseSupport use a connection of its own, which
+// is closed once the response has been read, by setting the request's Close
+// field. Other requests continue to reuse connections as usual. The cost is
+// the same as WithDisableKeepAlives, but paid only by the requests that need
+// it.
+func WithForceClose(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceCloseKey{}, true)
+}
+
+// WithForceCloseSupport returns an Option that makes the client honor
+// WithForceClose on the contexts of the requests it sends. It wraps the
+// client's transport, which is otherwise left as it is.
+func WithForceCloseSupport() Option {
+	return func(c *Client) *Client {
+		c.forceClose = true
+		return c
+	}
+}
+
+// forceCloser is the innermost layer of the transport of a client created
+// with WithForceCloseSupport, and applies WithForceClose to requests.
+type forceCloser struct {
+	next http.RoundTripper
+}
+
+func (fc forceCloser) RoundTrip(req *http.Request) (*http.Response, error) {
+	if force, _ := req.Context().Value(forceCloseKey{}).(bool); force && !req.Close {
+		req = cloneRequest(req)
+		req.Close = true
+	}
+	return fc.next.RoundTrip(req)
+}
+
+// CloseIdleConnections closes idle connections of the underlying transport,
+// if it supports doing so, allowing http.Client.CloseIdleConnections to reach
+// it.
+func (fc forceCloser) CloseIdleConnections() {
+	if ci, ok := fc.next.(interface{ CloseIdleConnections() }); ok {
+		ci.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,67 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+// countingServer returns a server along with a count of the connections it
+// has accepted.
+func countingServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var conns atomic.Int64
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts, &conns
+}
+
+func get(t *testing.T, c *client.Client, ctx context.Context, url string) {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestForceClose(t *testing.T) {
+	ts, conns := countingServer(t)
+	c := client.New(client.WithForceCloseSupport())
+
+	get(t, c, context.Background(), ts.URL)
+	get(t, c, context.Background(), ts.URL)
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("expected the connection to be reused, got %d connections", got)
+	}
+
+	get(t, c, client.WithForceClose(context.Background()), ts.URL)
+	get(t, c, context.Background(), ts.URL)
+	if got := conns.Load(); got != 2 {
+		t.Errorf("expected a new connection after a forced close, got %d connections", got)
+	}
+}
+
+func TestDisableKeepAlives(t *testing.T) {
+	ts, conns := countingServer(t)
+	c := client.New(client.WithDisableKeepAlives())
+
+	for range 3 {
+		get(t, c, context.Background(), ts.URL)
+	}
+	if got := conns.Load(); got != 3 {
+		t.Errorf("expected a connection per request, got %d connections", got)
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// WithConnMaxLifetime returns an Option that closes pooled connections once
+// they have been open for d, so the next request dials a new one. Behind a
+// load balancer that balances connections rather than requests, this spreads
+// a long-running client's requests across backends as they come and go.
+//
+// A connection that reaches its lifetime while idle is closed at once. One
+// that is in use finishes its request, and is closed when it is returned to
+// the pool. Requests are never interrupted.
+//
+// This only applies to HTTP/1 connections, and has no effect if a
+// RoundTripper other than an *http.Transport has been provided with
+// WithTransport.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(c *Client) *Client {
+		if !c.wrapDial(func(conn net.Conn) net.Conn { return newLifetimeConn(conn, d, c.clock) }) {
+			return c
+		}
+		return withTrace("conn_max_lifetime", func(req *http.Request) *httptrace.ClientTrace {
+			var lc *lifetimeConn
+			return &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					if lc = asLifetimeConn(info.Conn); lc != nil {
+						lc.setIdle(false)
+					}
+				},
+				PutIdleConn: func(err error) {
+					if err == nil && lc != nil {
+						lc.setIdle(true)
+					}
+				},
+			}
+		})(c)
+	}
+}
+
+// lifetimeConn is a connection that closes itself once its lifetime has passed
+// and it is idle.
+type lifetimeConn struct {
+	net.Conn
+	timer Timer
+	done  chan struct{}
+	once  sync.Once
+
+	mu      sync.Mutex
+	idle    bool
+	expired bool
+}
+
+func newLifetimeConn(conn net.Conn, d time.Duration, clk Clock) *lifetimeConn {
+	lc := &lifetimeConn{Conn: conn, timer: clk.NewTimer(d), done: make(chan struct{})}
+	go lc.watch()
+	return lc
+}
+
+// asLifetimeConn returns the lifetimeConn underlying conn, looking beneath
+// TLS and other wrappers, or nil if there is none.
+func asLifetimeConn(conn net.Conn) *lifetimeConn {
+	for {
+		if lc, ok := conn.(*lifetimeConn); ok {
+			return lc
+		}
+		nc, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		conn = nc.NetConn()
+	}
+}
+
+func (lc *lifetimeConn) watch() {
+	select {
+	case <-lc.timer.C():
+		lc.mu.Lock()
+		lc.expired = true
+		idle := lc.idle
+		lc.mu.Unlock()
+		if idle {
+			lc.Close()
+		}
+	case <-lc.done:
+	}
+}
+
+// setIdle records whether the connection is in the pool, and closes it if it
+// has been returned to the pool after its lifetime passed.
+func (lc *lifetimeConn) setIdle(idle bool) {
+	lc.mu.Lock()
+	lc.idle = idle
+	expired := lc.expired
+	lc.mu.Unlock()
+	if idle && expired {
+		lc.Close()
+	}
+}
+
+// NetConn returns the underlying connection.
+func (lc *lifetimeConn) NetConn() net.Conn {
+	return lc.Conn
+}
+
+func (lc *lifetimeConn) Close() error {
+	lc.once.Do(func() {
+		lc.timer.Stop()
+		close(lc.done)
+	})
+	return lc.Conn.Close()
+}
@@ -0,0 +1,87 @@
+package client_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+	"github.com/haleyrc/http/internal/clock"
+)
+
+func TestConnMaxLifetime(t *testing.T) {
+	var opened, closed atomic.Int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			opened.Add(1)
+		case http.StateClosed:
+			closed.Add(1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	clk := clock.NewFake(time.Now())
+	c := client.New(client.WithClock(clk), client.WithConnMaxLifetime(time.Minute))
+
+	get := func() *http.Response {
+		t.Helper()
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	finish := func(resp *http.Response) {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	waitClosed := func(n int32) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for closed.Load() < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d closed connections, got %d", n, closed.Load())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Within its lifetime, the connection is reused.
+	finish(get())
+	finish(get())
+	if n := opened.Load(); n != 1 {
+		t.Fatalf("expected 1 connection, got %d", n)
+	}
+
+	// Once its lifetime passes while idle, it is closed and the next request
+	// dials again.
+	clk.Advance(time.Minute)
+	waitClosed(1)
+	finish(get())
+	if n := opened.Load(); n != 2 {
+		t.Fatalf("expected a new connection after the lifetime, got %d", n)
+	}
+
+	// A connection whose lifetime passes mid-request finishes the request,
+	// and is closed once returned to the pool.
+	resp := get()
+	clk.Advance(time.Minute)
+	if n := closed.Load(); n != 1 {
+		t.Errorf("expected a busy connection to stay open, got %d closed", n)
+	}
+	finish(resp)
+	waitClosed(2)
+	finish(get())
+	if n := opened.Load(); n != 3 {
+		t.Errorf("expected a new connection after the lifetime, got %d", n)
+	}
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrLocalAddrFamily is returned, wrapped, when a client configured with
+// WithLocalAddr dials a target whose address family, IPv4 or IPv6, differs
+// from that of the local address.
+var ErrLocalAddrFamily = errors.New("client: target address family does not match local address")
+
+// WithLocalAddr returns an Option that makes every connection the client dials
+// originate from addr, a *net.TCPAddr or *net.IPAddr, for hosts with several
+// addresses or interfaces whose upstreams only accept connections from a
+// particular source IP. A zero port lets the system choose one.
+//
+// If addr has an IP address, only targets of the same address family can be
+// reached. Host names are resolved to that family only, and dialing an IP
+// address of the other family fails with ErrLocalAddrFamily. An addr with no
+// IP address only fixes the port.
+//
+// This replaces the transport's dial function with a dialer using the same
+// timeout and keep-alive settings as http.DefaultTransport, so it cannot be
+// combined with WithSOCKS5Proxy or a custom dial function. Connections it dials
+// are still passed through WithTCPNoDelay and WithConnMaxLifetime, whatever
+// the order of the options. It has no effect if a RoundTripper other than an
+// *http.Transport has been provided with WithTransport.
+func WithLocalAddr(addr net.Addr) Option {
+	return func(c *Client) *Client {
+		t := c.transport()
+		if t == nil {
+			return c
+		}
+
+		var local *net.TCPAddr
+		switch a := addr.(type) {
+		case *net.TCPAddr:
+			local = a
+		case *net.IPAddr:
+			local = &net.TCPAddr{IP: a.IP, Zone: a.Zone}
+		}
+		d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, LocalAddr: local}
+
+		t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if local == nil {
+				return nil, fmt.Errorf("client: unsupported local address %v of type %T", addr, addr)
+			}
+			if local.IP == nil {
+				return d.DialContext(ctx, network, address)
+			}
+			v4 := local.IP.To4() != nil
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			if ip := net.ParseIP(host); ip != nil && (ip.To4() != nil) != v4 {
+				return nil, fmt.Errorf("%w: cannot dial %s from %s", ErrLocalAddrFamily, address, local)
+			}
+			if network == "tcp" {
+				network = "tcp6"
+				if v4 {
+					network = "tcp4"
+				}
+			}
+			return d.DialContext(ctx, network, address)
+		}
+		return c
+	}
+}
@@ -0,0 +1,84 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestLocalAddr(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.RemoteAddr)
+	}))
+	defer ts.Close()
+
+	// Linux routes all of 127.0.0.0/8 to the loopback interface, so a second
+	// loopback address is usually available as a distinct source.
+	source := net.ParseIP("127.0.0.2")
+	if ln, err := net.Listen("tcp", "127.0.0.2:0"); err != nil {
+		source = net.ParseIP("127.0.0.1")
+	} else {
+		ln.Close()
+	}
+
+	c := client.New(client.WithLocalAddr(&net.TCPAddr{IP: source}))
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(string(body), source.String()+":") {
+		t.Errorf("expected the connection to come from %s, got %s", source, body)
+	}
+}
+
+func TestLocalAddrFamilyMismatch(t *testing.T) {
+	c := client.New(client.WithLocalAddr(&net.IPAddr{IP: net.ParseIP("127.0.0.1")}))
+	_, err := c.Get("http://[::1]:1/")
+	if !errors.Is(err, client.ErrLocalAddrFamily) {
+		t.Errorf("expected error %v, got %v", client.ErrLocalAddrFamily, err)
+	}
+}
+
+func TestLocalAddrNoIP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := client.New(client.WithLocalAddr(&net.TCPAddr{}))
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected an address with no IP not to restrict the family, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestLocalAddrKeepsDialWrappers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	var conn net.Conn
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { conn = info.Conn }}
+	c := client.New(
+		client.WithConnMaxLifetime(time.Hour),
+		client.WithLocalAddr(&net.TCPAddr{IP: net.ParseIP("127.0.0.1")}),
+	)
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, ok := conn.(*net.TCPConn); ok {
+		t.Error("expected the connection to be wrapped by WithConnMaxLifetime")
+	}
+}
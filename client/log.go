@@ -0,0 +1,10 @@
+package client
+
+// Logger is a minimal structured logger. Key-value pairs are provided as
+// alternating keys and values, in the style of log/slog. A server.Logger
+// satisfies this interface.
+type Logger interface {
+	Info(msg string, keyvals ...any)
+	Warn(msg string, keyvals ...any)
+	Error(msg string, keyvals ...any)
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryCache is an in-memory Cache that evicts the least recently used
+// entries once it holds more than a maximum number of entries or bytes. It is
+// safe for concurrent use.
+type MemoryCache struct {
+	maxEntries int
+	maxBytes   int64
+
+	mu    sync.Mutex
+	lru   *list.List // of *memoryEntry, most recently used first
+	items map[string]*list.Element
+	size  int64
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+// size is the number of bytes an entry counts towards the cache's limit: its
+// key and the serialized response, including the status line and headers.
+func (e *memoryEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// NewMemoryCache returns a MemoryCache holding at most maxEntries entries
+// totalling at most maxBytes bytes. A limit that is not positive is not
+// enforced. An entry larger than maxBytes on its own is never stored.
+func NewMemoryCache(maxEntries int, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		lru:        list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// WithMemoryCache returns an Option that caches responses, as with WithCache,
+// in a new MemoryCache with the given limits.
+func WithMemoryCache(maxEntries int, maxBytes int64) Option {
+	return WithCache(NewMemoryCache(maxEntries, maxBytes))
+}
+
+// Get implements Cache, marking the entry as recently used.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*memoryEntry).value, true
+}
+
+// Set implements Cache, evicting the least recently used entries as needed to
+// stay within the cache's limits.
+func (c *MemoryCache) Set(key string, resp []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+
+	e := &memoryEntry{key: key, value: resp}
+	if c.maxBytes > 0 && e.size() > c.maxBytes {
+		return
+	}
+	c.items[key] = c.lru.PushFront(e)
+	c.size += e.size()
+
+	for (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) || (c.maxBytes > 0 && c.size > c.maxBytes) {
+		c.remove(c.lru.Back().Value.(*memoryEntry).key)
+	}
+}
+
+// Delete implements Cache.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+// Len returns the number of entries in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Size returns the number of bytes the entries in the cache account for.
+func (c *MemoryCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// remove must be called with c.mu held.
+func (c *MemoryCache) remove(key string) {
+	el, ok := c.items[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(el)
+	delete(c.items, key)
+	c.size -= el.Value.(*memoryEntry).size()
+}
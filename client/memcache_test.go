@@ -0,0 +1,89 @@
+package client_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestMemoryCacheEvictsByCount(t *testing.T) {
+	c := client.NewMemoryCache(2, 0)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a") // b is now the least recently used
+	c.Set("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+}
+
+func TestMemoryCacheEvictsByBytes(t *testing.T) {
+	// Each entry is a 1 byte key and a 9 byte value.
+	c := client.NewMemoryCache(0, 25)
+	c.Set("a", []byte("123456789"))
+	c.Set("b", []byte("123456789"))
+	if size := c.Size(); size != 20 {
+		t.Fatalf("expected 20 bytes, got %d", size)
+	}
+
+	c.Set("c", []byte("123456789"))
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be evicted")
+	}
+	if size := c.Size(); size != 20 {
+		t.Errorf("expected 20 bytes after eviction, got %d", size)
+	}
+
+	// Replacing an entry accounts for the difference in size.
+	c.Set("c", []byte("1"))
+	if size := c.Size(); size != 12 {
+		t.Errorf("expected 12 bytes after replacement, got %d", size)
+	}
+
+	// An entry that could never fit is not stored, and evicts nothing.
+	c.Set("d", make([]byte, 30))
+	if _, ok := c.Get("d"); ok {
+		t.Error("expected an oversized entry not to be stored")
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+}
+
+func TestMemoryCacheConcurrent(t *testing.T) {
+	c := client.NewMemoryCache(50, 1<<10)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				key := fmt.Sprintf("%d-%d", g, i%100)
+				c.Set(key, []byte(key))
+				c.Get(key)
+				if i%7 == 0 {
+					c.Delete(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := c.Len(); n > 50 {
+		t.Errorf("expected at most 50 entries, got %d", n)
+	}
+	if size := c.Size(); size > 1<<10 {
+		t.Errorf("expected at most %d bytes, got %d", 1<<10, size)
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ClientMetricsRecorder receives a measurement of every attempt made by a
+// Client configured with WithClientMetrics.
+type ClientMetricsRecorder interface {
+	// ObserveRequest is called once each attempt has a response or has
+	// failed. host is the host, and port if any, the request was sent to, and
+	// attempt counts from 1, going up with each retry made by WithRetry.
+	// status is 0 if there was no response, in which case err is the error
+	// the attempt failed with. d is the time until the response header was
+	// received, not including reading the body.
+	ObserveRequest(host, method string, attempt, status int, d time.Duration, err error)
+}
+
+type attemptCounterKey struct{}
+
+// WithClientMetrics returns an Option that times every attempt the client
+// makes and reports it to rec, so the health of each upstream, such as its
+// error rate and latency, can be graphed. Retries made by WithRetry are
+// reported as separate attempts, each with its attempt number.
+func WithClientMetrics(rec ClientMetricsRecorder) Option {
+	return func(c *Client) *Client {
+		c = withNamedMiddleware("client_metrics", func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				ctx := context.WithValue(req.Context(), attemptCounterKey{}, new(atomic.Int64))
+				return next.RoundTrip(req.WithContext(ctx))
+			})
+		})(c)
+		c.attempt = append(c.attempt, layer{name: "client_metrics_attempt", mw: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				attempt := 1
+				if n, ok := req.Context().Value(attemptCounterKey{}).(*atomic.Int64); ok {
+					attempt = int(n.Add(1))
+				}
+				start := c.clock.Now()
+				resp, err := next.RoundTrip(req)
+				var status int
+				if resp != nil {
+					status = resp.StatusCode
+				}
+				rec.ObserveRequest(req.URL.Host, req.Method, attempt, status, c.clock.Now().Sub(start), err)
+				return resp, err
+			})
+		}})
+		return c
+	}
+}
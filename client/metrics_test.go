@@ -0,0 +1,64 @@
+package client_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+type attemptRecorder struct {
+	mu       sync.Mutex
+	attempts []string
+}
+
+func (r *attemptRecorder) ObserveRequest(host, method string, attempt, status int, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, fmt.Sprintf("%s %s #%d %d %t", method, host, attempt, status, err != nil))
+}
+
+func TestClientMetricsRetries(t *testing.T) {
+	ts, _ := flaky(t, 2)
+	u, _ := url.Parse(ts.URL)
+
+	rec := &attemptRecorder{}
+	c := client.New(client.WithClientMetrics(rec), client.WithRetry(fastRetry))
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	want := []string{
+		"GET " + u.Host + " #1 503 false",
+		"GET " + u.Host + " #2 503 false",
+		"GET " + u.Host + " #3 200 false",
+		"GET " + u.Host + " #1 200 false",
+	}
+	if fmt.Sprint(rec.attempts) != fmt.Sprint(want) {
+		t.Errorf("expected %q, got %q", want, rec.attempts)
+	}
+}
+
+func TestClientMetricsError(t *testing.T) {
+	failing := client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	rec := &attemptRecorder{}
+	c := client.New(client.WithTransport(failing), client.WithClientMetrics(rec))
+	if _, err := c.Get("http://upstream.test/"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if want := "[GET upstream.test #1 0 true]"; fmt.Sprint(rec.attempts) != want {
+		t.Errorf("expected %s, got %v", want, rec.attempts)
+	}
+}
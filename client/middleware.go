@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// ErrRoundTripPanic is returned, wrapped in a *PanicError, when a middleware
+// panics during a round trip.
+var ErrRoundTripPanic = errors.New("client: round trip panicked")
+
+// PanicError records a panic recovered from a middleware during a round trip.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrRoundTripPanic, e.Value)
+}
+
+// Unwrap allows errors.Is to match PanicError against ErrRoundTripPanic.
+func (e *PanicError) Unwrap() error {
+	return ErrRoundTripPanic
+}
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as
+// http.RoundTrippers.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a RoundTripper to add behavior to every request made by the
+// client.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// WithMiddleware returns an Option that adds the provided middleware to the
+// client's transport. Middleware is applied in the order it was added, so the
+// first middleware added is the first to see each request.
+//
+// By default, a panic in any middleware is recovered and returned from the
+// round trip as a *PanicError. Use WithPanicPropagation to disable this.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *Client) *Client {
+		for _, mw := range mws {
+			c.middleware = append(c.middleware, layer{name: "custom", mw: mw})
+		}
+		return c
+	}
+}
+
+// layer is a middleware along with the name it is listed under by Describe.
+type layer struct {
+	name string
+	mw   Middleware
+}
+
+// withNamedMiddleware returns an Option that adds mw to the client's
+// middleware, as WithMiddleware does, listing it as name.
+func withNamedMiddleware(name string, mw Middleware) Option {
+	return func(c *Client) *Client {
+		c.middleware = append(c.middleware, layer{name: name, mw: mw})
+		return c
+	}
+}
+
+// WithPanicPropagation returns an Option that disables recovering panics in
+// client middleware, allowing them to propagate to the calling goroutine.
+func WithPanicPropagation() Option {
+	return func(c *Client) *Client {
+		c.propagatePanics = true
+		return c
+	}
+}
+
+// chain wraps rt in the client's middleware such that the first middleware is
+// the outermost. Per-attempt middleware, such as request interceptors, wraps
+// rt directly, beneath any retries, while the rest wraps the retries. Response
+// interceptors wrap everything.
+func (c *Client) chain(rt http.RoundTripper) http.RoundTripper {
+	if len(c.middleware) == 0 && len(c.attempt) == 0 && len(c.outer) == 0 && c.retry == nil && !c.forceClose {
+		return rt
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if c.forceClose {
+		rt = forceCloser{rt}
+	}
+	rt = c.wrap(rt, c.attempt)
+	if c.retry != nil {
+		rt = &retrier{next: rt, policy: *c.retry, budget: c.retryBudget, clock: c.clock}
+	}
+	rt = c.wrap(rt, c.middleware)
+	return c.wrap(rt, c.outer)
+}
+
+// wrap wraps rt in mws such that the first is the outermost, recovering panics
+// in each unless propagation is enabled.
+func (c *Client) wrap(rt http.RoundTripper, mws []layer) http.RoundTripper {
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i].mw(rt)
+		if !c.propagatePanics {
+			rt = recoverer(rt)
+		}
+	}
+	return rt
+}
+
+// recoverer wraps rt such that a panic during RoundTrip is converted to a
+// *PanicError.
+func recoverer(rt http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				resp, err = nil, &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+		return rt.RoundTrip(req)
+	})
+}
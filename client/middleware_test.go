@@ -0,0 +1,50 @@
+package client_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func panicking(next http.RoundTripper) http.RoundTripper {
+	return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		panic("boom")
+	})
+}
+
+func TestMiddlewarePanicRecovered(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := client.New(client.WithMiddleware(panicking))
+	_, err := c.Get(ts.URL)
+	if !errors.Is(err, client.ErrRoundTripPanic) {
+		t.Fatalf("expected error %v, got %v", client.ErrRoundTripPanic, err)
+	}
+
+	var perr *client.PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a *PanicError, got %T", err)
+	}
+	if perr.Value != "boom" {
+		t.Errorf("expected panic value %q, got %v", "boom", perr.Value)
+	}
+}
+
+func TestMiddlewarePanicPropagation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Errorf("expected panic %q, got %v", "boom", v)
+		}
+	}()
+
+	c := client.New(client.WithMiddleware(panicking), client.WithPanicPropagation())
+	c.Get(ts.URL)
+	t.Error("expected a panic")
+}
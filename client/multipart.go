@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FileUpload describes a single file part of a multipart upload.
+type FileUpload struct {
+	// FieldName is the form field the file is sent under.
+	FieldName string
+
+	// FileName is the file name reported to the server.
+	FileName string
+
+	// ContentType is the type of the file content. If empty,
+	// application/octet-stream is used.
+	ContentType string
+
+	// Content is the file content. It is streamed to the server rather than
+	// buffered in memory. If Content also implements io.Seeker, the request
+	// body can be replayed for redirects and retries.
+	Content io.Reader
+}
+
+// PostMultipart sends a multipart/form-data POST to url containing the provided
+// fields and files. The body is streamed, so file contents are never buffered
+// in memory in full.
+//
+// If every file's Content implements io.Seeker, the request's GetBody is set so
+// that the body can be rewound and sent again.
+func (c *Client) PostMultipart(ctx context.Context, url string, fields map[string]string, files []FileUpload) (*http.Response, error) {
+	boundary := multipart.NewWriter(nil).Boundary()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+
+	offsets, seekable := seekOffsets(files)
+	body := streamMultipart(boundary, fields, files)
+	req.Body = body
+	if seekable {
+		var mu sync.Mutex
+		req.GetBody = func() (io.ReadCloser, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			// The previous body's writer may still be copying from the files,
+			// so it is stopped before they are rewound.
+			body.stop()
+			for i, f := range files {
+				if _, err := f.Content.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+			body = streamMultipart(boundary, fields, files)
+			return body, nil
+		}
+	}
+
+	return c.Do(req)
+}
+
+// seekOffsets returns the current offset of each file's content, and whether
+// all of them are seekable.
+func seekOffsets(files []FileUpload) ([]int64, bool) {
+	offsets := make([]int64, len(files))
+	for i, f := range files {
+		s, ok := f.Content.(io.Seeker)
+		if !ok {
+			return nil, false
+		}
+		off, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, false
+		}
+		offsets[i] = off
+	}
+	return offsets, true
+}
+
+// multipartBody is a multipart body being written by a separate goroutine.
+type multipartBody struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// stop closes the body and waits for its writer to return.
+func (b *multipartBody) stop() {
+	b.Close()
+	<-b.done
+}
+
+// streamMultipart returns a reader that produces the multipart body as it is
+// read, writing it from a separate goroutine.
+func streamMultipart(boundary string, fields map[string]string, files []FileUpload) *multipartBody {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(writeMultipart(pw, boundary, fields, files))
+	}()
+	return &multipartBody{PipeReader: pr, done: done}
+}
+
+func writeMultipart(w io.Writer, boundary string, fields map[string]string, files []FileUpload) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := mw.WriteField(k, fields[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range files {
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+escapeQuotes(f.FieldName)+`"; filename="`+escapeQuotes(f.FileName)+`"`)
+		h.Set("Content-Type", ct)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.Content); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
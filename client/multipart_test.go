@@ -0,0 +1,108 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestPostMultipart(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Redirect the first request to force the body to be replayed.
+		calls++
+		if calls == 1 {
+			http.Redirect(w, r, "/upload", http.StatusTemporaryRedirect)
+			return
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parsing form: %v", err)
+			return
+		}
+		if got := r.FormValue("name"); got != "widget" {
+			t.Errorf("expected field %q, got %q", "widget", got)
+		}
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("reading file: %v", err)
+			return
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		if string(b) != "file contents" {
+			t.Errorf("expected file contents %q, got %q", "file contents", b)
+		}
+		if fh.Filename != "widget.txt" {
+			t.Errorf("expected file name %q, got %q", "widget.txt", fh.Filename)
+		}
+	}))
+	defer ts.Close()
+
+	c := client.New()
+	resp, err := c.PostMultipart(context.Background(), ts.URL,
+		map[string]string{"name": "widget"},
+		[]client.FileUpload{{
+			FieldName: "file",
+			FileName:  "widget.txt",
+			Content:   strings.NewReader("file contents"),
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestPostMultipartReplayUnread(t *testing.T) {
+	content := strings.Repeat("x", 4<<20)
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Redirect the first request without reading the body, so the body
+		// is replayed while the first copy may still be being written.
+		calls++
+		if calls == 1 {
+			http.Redirect(w, r, "/upload", http.StatusTemporaryRedirect)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parsing form: %v", err)
+			return
+		}
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("reading file: %v", err)
+			return
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		if len(b) != len(content) {
+			t.Errorf("expected %d bytes of file contents, got %d", len(content), len(b))
+		}
+	}))
+	defer ts.Close()
+
+	c := client.New()
+	resp, err := c.PostMultipart(context.Background(), ts.URL, nil, []client.FileUpload{{
+		FieldName: "file",
+		FileName:  "big.txt",
+		Content:   strings.NewReader(content),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import "net"
+
+// WithTCPNoDelay returns an Option that sets TCP_NODELAY on every connection
+// the client dials. Go already disables Nagle's algorithm by default, sending
+// small writes immediately for low latency, so this is mainly useful with
+// false, to re-enable Nagle's algorithm and let the kernel coalesce small
+// writes when bandwidth efficiency matters more than latency.
+//
+// This has no effect on connections that are not TCP, or if a RoundTripper
+// other than an *http.Transport has been provided with WithTransport.
+func WithTCPNoDelay(noDelay bool) Option {
+	return func(c *Client) *Client {
+		c.wrapDial(func(conn net.Conn) net.Conn {
+			if tc := tcpConn(conn); tc != nil {
+				tc.SetNoDelay(noDelay)
+			}
+			return conn
+		})
+		return c
+	}
+}
+
+// tcpConn returns the *net.TCPConn underlying conn, looking beneath wrappers
+// with a NetConn method, or nil if there is none.
+func tcpConn(conn net.Conn) *net.TCPConn {
+	for {
+		if tc, ok := conn.(*net.TCPConn); ok {
+			return tc
+		}
+		nc, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		conn = nc.NetConn()
+	}
+}
@@ -0,0 +1,61 @@
+//go:build linux || darwin
+
+package client_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+// noDelay reports whether TCP_NODELAY is set on conn.
+func noDelay(t *testing.T, conn net.Conn) bool {
+	t.Helper()
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	var serr error
+	raw.Control(func(fd uintptr) {
+		v, serr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+	})
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	return v != 0
+}
+
+func TestTCPNoDelay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	for _, want := range []bool{false, true} {
+		conns := make(chan net.Conn, 1)
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{Timeout: time.Second}).DialContext(ctx, network, addr)
+			if err == nil {
+				conns <- conn
+			}
+			return conn, err
+		}
+
+		c := client.New(client.WithTransport(transport), client.WithTCPNoDelay(want))
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got := noDelay(t, <-conns); got != want {
+			t.Errorf("expected TCP_NODELAY %t, got %t", want, got)
+		}
+	}
+}
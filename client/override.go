@@ -0,0 +1,33 @@
+package client
+
+import "net/http"
+
+// MethodOverrideHeader is the header WithMethodOverride uses to carry the real
+// method of a request sent as a POST.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// WithMethodOverride returns an Option that sends PUT, PATCH and DELETE
+// requests as POST, with the real method in the X-HTTP-Method-Override
+// header, for servers behind proxies that block those methods. The server
+// must honor the header, as the MethodOverride middleware of this module's
+// server package does.
+//
+// The override is applied beneath WithRetry, so the retrier still sees the
+// real method and retries PUT and DELETE requests as idempotent.
+func WithMethodOverride() Option {
+	return func(c *Client) *Client {
+		c.attempt = append(c.attempt, layer{name: "method_override", mw: func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				switch req.Method {
+				case http.MethodPut, http.MethodPatch, http.MethodDelete:
+					method := req.Method
+					req = cloneRequest(req)
+					req.Method = http.MethodPost
+					req.Header.Set(MethodOverrideHeader, method)
+				}
+				return next.RoundTrip(req)
+			})
+		}})
+		return c
+	}
+}
@@ -0,0 +1,63 @@
+package client_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestMethodOverride(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Override", r.Header.Get(client.MethodOverrideHeader))
+	}))
+	defer ts.Close()
+
+	c := client.New(client.WithMethodOverride())
+	for _, tt := range []struct {
+		method, wantMethod, wantOverride string
+	}{
+		{"GET", "GET", ""},
+		{"POST", "POST", ""},
+		{"PUT", "POST", "PUT"},
+		{"PATCH", "POST", "PATCH"},
+		{"DELETE", "POST", "DELETE"},
+	} {
+		req, _ := http.NewRequest(tt.method, ts.URL, strings.NewReader("body"))
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("X-Method"); got != tt.wantMethod {
+			t.Errorf("%s: expected to send %s, sent %s", tt.method, tt.wantMethod, got)
+		}
+		if got := resp.Header.Get("X-Override"); got != tt.wantOverride {
+			t.Errorf("%s: expected override %q, got %q", tt.method, tt.wantOverride, got)
+		}
+		if req.Method != tt.method {
+			t.Errorf("expected the caller's request to be left alone, got %s", req.Method)
+		}
+	}
+}
+
+func TestMethodOverrideRetry(t *testing.T) {
+	ts, hits := flaky(t, 2)
+
+	c := client.New(client.WithMethodOverride(), client.WithRetry(fastRetry))
+	req, _ := http.NewRequest("DELETE", ts.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("expected the DELETE to be retried as idempotent, got %d attempts", got)
+	}
+}
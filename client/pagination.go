@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MaxPages is the maximum number of pages GetAllPages will fetch before giving
+// up with ErrTooManyPages, protecting against servers that link in a cycle.
+const MaxPages = 1000
+
+// ErrTooManyPages is returned by GetAllPages when a resource has more than
+// MaxPages pages.
+var ErrTooManyPages = errors.New("client: too many pages")
+
+// GetAllPages fetches url and calls handle with the response, then follows the
+// rel="next" link in the response's Link header (RFC 8288) and repeats until a
+// page has no next link. Relative links are resolved against the URL of the
+// page they appear on.
+//
+// A non-2xx response stops pagination with a *StatusError, as does any error
+// returned by handle. Each response body is drained and closed after handle
+// returns, so handle need not close it.
+func (c *Client) GetAllPages(ctx context.Context, url string, handle func(resp *http.Response) error) error {
+	next := url
+	for page := 0; next != ""; page++ {
+		if page == MaxPages {
+			return ErrTooManyPages
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			return err
+		}
+
+		next, err = c.handlePage(resp, handle)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlePage checks the status of resp, passes it to handle, and returns the
+// absolute URL of the next page, if any.
+func (c *Client) handlePage(resp *http.Response, handle func(resp *http.Response) error) (string, error) {
+	defer drainAndClose(resp.Body)
+
+	if err := checkStatus(resp); err != nil {
+		return "", err
+	}
+	if err := handle(resp); err != nil {
+		return "", err
+	}
+
+	for _, l := range parseLinks(resp.Header.Values("Link")) {
+		if !l.hasRel("next") {
+			continue
+		}
+		u, err := resp.Request.URL.Parse(l.target)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	return "", nil
+}
+
+// link is a single link from a Link header.
+type link struct {
+	target string
+	params map[string]string
+}
+
+// hasRel reports whether rel is one of the space-separated relation types of l.
+func (l link) hasRel(rel string) bool {
+	for _, r := range strings.Fields(l.params["rel"]) {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLinks parses the values of one or more Link headers. Each value may
+// hold several comma-separated links of the form
+//
+//	<target>; param=value; param="quoted, value"
+//
+// Malformed links are skipped.
+func parseLinks(values []string) []link {
+	var links []link
+	for _, v := range values {
+		for len(v) > 0 {
+			var l link
+			var ok bool
+			l, v, ok = parseLink(v)
+			if ok {
+				links = append(links, l)
+			}
+		}
+	}
+	return links
+}
+
+// parseLink parses the first link in s, returning it along with the remainder
+// of s after the comma that terminates it.
+func parseLink(s string) (link, string, bool) {
+	s = strings.TrimLeft(s, " \t,")
+	if !strings.HasPrefix(s, "<") {
+		// Skip to the next link.
+		_, rest, _ := cutUnquoted(s, ',')
+		return link{}, rest, false
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return link{}, "", false
+	}
+	l := link{target: s[1:end], params: make(map[string]string)}
+	s = s[end+1:]
+
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return l, "", true
+		}
+		switch s[0] {
+		case ',':
+			return l, s[1:], true
+		case ';':
+			s = s[1:]
+		default:
+			// Garbage after the target or a parameter.
+			_, rest, _ := cutUnquoted(s, ',')
+			return l, rest, true
+		}
+
+		var param string
+		param, s = cutParam(s)
+		k, v, _ := strings.Cut(param, "=")
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+		if uq, ok := unquote(v); ok {
+			v = uq
+		}
+		// Per RFC 8288, only the first occurrence of a parameter counts.
+		if _, dup := l.params[k]; k != "" && !dup {
+			l.params[k] = v
+		}
+	}
+}
+
+// cutParam returns the parameter at the start of s, ending at the first
+// unquoted ';' or ',', and the remainder of s starting with that delimiter.
+func cutParam(s string) (string, string) {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inQuotes:
+			i++
+		case c == '"':
+			inQuotes = !inQuotes
+		case (c == ';' || c == ',') && !inQuotes:
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// cutUnquoted is like strings.Cut, but ignores sep inside quoted strings.
+func cutUnquoted(s string, sep byte) (string, string, bool) {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inQuotes:
+			i++
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == sep && !inQuotes:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// unquote removes the quotes and backslash escapes from an HTTP quoted string.
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s, false
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
@@ -0,0 +1,56 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestGetAllPages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		w.Header().Add("Link", `</items?page=1>; title="first, really; yes"; rel="first"`)
+		if page < 3 {
+			w.Header().Add("Link", fmt.Sprintf(`</items?page=3>; rel=last, </items?page=%d>; rel="prev next"; rel=ignored`, page+1))
+		}
+		fmt.Fprintf(w, "page %d", page)
+	}))
+	defer ts.Close()
+
+	var pages []string
+	c := client.New()
+	err := c.GetAllPages(context.Background(), ts.URL+"/items", func(resp *http.Response) error {
+		b, _ := io.ReadAll(resp.Body)
+		pages = append(pages, string(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(pages) != "[page 1 page 2 page 3]" {
+		t.Errorf("expected 3 pages, got %v", pages)
+	}
+}
+
+func TestGetAllPagesCycle(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `</items>; rel="next"`)
+	}))
+	defer ts.Close()
+
+	c := client.New()
+	err := c.GetAllPages(context.Background(), ts.URL+"/items", func(*http.Response) error { return nil })
+	if !errors.Is(err, client.ErrTooManyPages) {
+		t.Errorf("expected error %v, got %v", client.ErrTooManyPages, err)
+	}
+}
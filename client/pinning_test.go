@@ -0,0 +1,59 @@
+package client_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestPinnedCertificates(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	pin := client.SPKIHash(ts.Certificate())
+	other := sha256.Sum256([]byte("some other key"))
+	otherPin := base64.StdEncoding.EncodeToString(other[:])
+
+	for _, tt := range []struct {
+		name    string
+		pins    []string
+		wantErr error
+	}{
+		{"match", []string{pin}, nil},
+		{"rotation", []string{otherPin, strings.TrimPrefix(pin, "sha256/")}, nil},
+		{"mismatch", []string{otherPin}, client.ErrCertificatePinMismatch},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := client.New(
+				client.WithTransport(ts.Client().Transport.(*http.Transport).Clone()),
+				client.WithPinnedCertificates(tt.pins...),
+			)
+			resp, err := c.Get(ts.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPinnedCertificatesInvalidPin(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := client.New(
+		client.WithTransport(ts.Client().Transport.(*http.Transport).Clone()),
+		client.WithPinnedCertificates(client.SPKIHash(ts.Certificate()), "not a pin"),
+	)
+	if _, err := c.Get(ts.URL); err == nil || !strings.Contains(err.Error(), "invalid certificate pin") {
+		t.Errorf("expected an invalid pin error, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/haleyrc/http/internal/propagation"
+)
+
+// PropagationContext is a bag of correlation metadata, such as a trace ID,
+// tenant or request ID, keyed by the header it is propagated in. The server
+// package's WithPropagation fills one from each incoming request, and
+// WithPropagation sends it on every request made with that request's context.
+type PropagationContext = propagation.Bag
+
+// WithPropagationContext returns a copy of ctx carrying bag, merged over any
+// bag ctx already carries, for requests made with it by a client configured
+// with WithPropagation.
+func WithPropagationContext(ctx context.Context, bag PropagationContext) context.Context {
+	return propagation.NewContext(ctx, bag)
+}
+
+// PropagationFromContext returns the bag carried by ctx, or nil if there is
+// none. The bag must not be modified.
+func PropagationFromContext(ctx context.Context) PropagationContext {
+	return propagation.FromContext(ctx)
+}
+
+// WithPropagation returns an Option that, for every request, sends the value
+// of each of headers found in the PropagationContext of the request's context,
+// closing the loop with the server package's WithPropagation so that
+// correlation metadata follows a request across services. Headers already set
+// on the request are not overridden, and headers missing from the bag are
+// omitted.
+func WithPropagation(headers ...string) Option {
+	return withNamedMiddleware("propagation", func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			bag := propagation.FromContext(req.Context())
+			if len(bag) == 0 {
+				return next.RoundTrip(req)
+			}
+			cloned := false
+			for _, h := range headers {
+				v, ok := bag[http.CanonicalHeaderKey(h)]
+				if !ok || v == "" || req.Header.Get(h) != "" {
+					continue
+				}
+				if !cloned {
+					req = cloneRequest(req)
+					cloned = true
+				}
+				req.Header.Set(h, v)
+			}
+			return next.RoundTrip(req)
+		})
+	})
+}
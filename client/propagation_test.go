@@ -0,0 +1,48 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestPropagation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Got-Tenant", r.Header.Get("X-Tenant"))
+		w.Header().Set("X-Got-Trace", r.Header.Get("X-Trace-Id"))
+		w.Header().Set("X-Got-Secret", r.Header.Get("X-Secret"))
+	}))
+	defer ts.Close()
+
+	ctx := client.WithPropagationContext(context.Background(), client.PropagationContext{
+		"x-tenant":   "acme",
+		"X-Trace-Id": "abc",
+		"X-Secret":   "hunter2",
+	})
+	ctx = client.WithPropagationContext(ctx, client.PropagationContext{"X-Trace-Id": "def"})
+	if got := client.PropagationFromContext(ctx)["X-Tenant"]; got != "acme" {
+		t.Errorf("expected merged bags with canonical keys, got %v", client.PropagationFromContext(ctx))
+	}
+
+	c := client.New(client.WithPropagation("X-Tenant", "X-Trace-Id"))
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	req.Header.Set("X-Tenant", "explicit")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for header, want := range map[string]string{
+		"X-Got-Tenant": "explicit",
+		"X-Got-Trace":  "def",
+		"X-Got-Secret": "",
+	} {
+		if got := resp.Header.Get(header); got != want {
+			t.Errorf("%s: expected %q, got %q", header, want, got)
+		}
+	}
+}
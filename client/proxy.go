@@ -0,0 +1,114 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// WithProxy returns an Option that sends every request through the proxy at u,
+// regardless of the environment.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithProxy(u *url.URL) Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil {
+			t.Proxy = http.ProxyURL(u)
+			c.proxy = u.Redacted()
+		}
+		return c
+	}
+}
+
+// WithProxyFromEnvironment returns an Option that selects a proxy for each
+// request based on the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment
+// variables (or their lowercase equivalents), read when the option is applied.
+//
+// The default transport already behaves this way, but applying this option
+// makes the behavior explicit and restores it after a call to WithProxy or
+// WithNoProxy.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithProxyFromEnvironment() Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil {
+			proxy := httpproxy.FromEnvironment().ProxyFunc()
+			c.proxy = "environment"
+			t.Proxy = func(req *http.Request) (*url.URL, error) {
+				return proxy(req.URL)
+			}
+		}
+		return c
+	}
+}
+
+// WithNoProxy returns an Option that connects directly to every host, ignoring
+// any proxy configured in the environment.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithNoProxy() Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil {
+			t.Proxy = nil
+			c.proxy = "none"
+		}
+		return c
+	}
+}
+
+// ProxyAuth holds credentials for authenticating with a proxy.
+type ProxyAuth struct {
+	Username string
+	Password string
+}
+
+// WithSOCKS5Proxy returns an Option that dials every connection through the
+// SOCKS5 proxy at addr, authenticating with auth if it is non-nil. The proxy
+// handshake and dial both respect the request context's deadline. TLS, if
+// used, runs end-to-end over the proxied connection.
+//
+// Because the proxy operates at the TCP layer, any HTTP proxy configured on
+// the transport, including from the environment, is disabled.
+//
+// This has no effect if a RoundTripper other than an *http.Transport has been
+// provided with WithTransport.
+func WithSOCKS5Proxy(addr string, auth *ProxyAuth) Option {
+	return func(c *Client) *Client {
+		t := c.transport()
+		if t == nil {
+			return c
+		}
+
+		var pauth *proxy.Auth
+		if auth != nil {
+			pauth = &proxy.Auth{User: auth.Username, Password: auth.Password}
+		}
+		forward := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		d, err := proxy.SOCKS5("tcp", addr, pauth, forward)
+		if err != nil {
+			// SOCKS5 only fails for unsupported networks, and "tcp" is
+			// always supported.
+			panic(err)
+		}
+
+		t.Proxy = nil
+		t.DialContext = d.(proxy.ContextDialer).DialContext
+		c.proxy = (&url.URL{Scheme: "socks5", User: socksUser(auth), Host: addr}).Redacted()
+		return c
+	}
+}
+
+// socksUser returns the userinfo for auth, for describing the proxy.
+func socksUser(auth *ProxyAuth) *url.Userinfo {
+	if auth == nil {
+		return nil
+	}
+	return url.UserPassword(auth.Username, auth.Password)
+}
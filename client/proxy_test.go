@@ -0,0 +1,199 @@
+package client_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+// proxyFor returns the proxy c's transport selects for target.
+func proxyFor(t *testing.T, c *client.Client, target string) string {
+	t.Helper()
+	tr := c.Transport.(*http.Transport)
+	if tr.Proxy == nil {
+		return ""
+	}
+	req, _ := http.NewRequest("GET", target, nil)
+	u, err := tr.Proxy(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+func TestProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3129")
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	forced, _ := url.Parse("http://forced:8080")
+
+	tests := []struct {
+		name   string
+		opt    client.Option
+		target string
+		want   string
+	}{
+		{"env http", client.WithProxyFromEnvironment(), "http://api.example.com", "http://env-proxy:3128"},
+		{"env https", client.WithProxyFromEnvironment(), "https://api.example.com", "http://env-proxy:3129"},
+		{"env no proxy", client.WithProxyFromEnvironment(), "http://internal.example.com", ""},
+		{"explicit", client.WithProxy(forced), "http://internal.example.com", "http://forced:8080"},
+		{"disabled", client.WithNoProxy(), "http://api.example.com", ""},
+	}
+	for _, tt := range tests {
+		c := client.New(tt.opt)
+		if got := proxyFor(t, c, tt.target); got != tt.want {
+			t.Errorf("%s: expected proxy %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+// socks5Server is a minimal SOCKS5 proxy supporting the CONNECT command with
+// either no authentication or username/password authentication.
+type socks5Server struct {
+	ln       net.Listener
+	user     string
+	password string
+
+	mu    sync.Mutex
+	conns int
+}
+
+func newSOCKS5Server(t *testing.T, user, password string) *socks5Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socks5Server{ln: ln, user: user, password: password}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(c)
+		}
+	}()
+	return s
+}
+
+func (s *socks5Server) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func (s *socks5Server) handle(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	// Greeting: version, number of methods, methods.
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return
+	}
+
+	if s.user == "" {
+		c.Write([]byte{5, 0})
+	} else {
+		c.Write([]byte{5, 2})
+		// Username/password: version, ulen, user, plen, password.
+		var ver, n [1]byte
+		io.ReadFull(r, ver[:])
+		io.ReadFull(r, n[:])
+		user := make([]byte, n[0])
+		io.ReadFull(r, user)
+		io.ReadFull(r, n[:])
+		pass := make([]byte, n[0])
+		io.ReadFull(r, pass)
+		if string(user) != s.user || string(pass) != s.password {
+			c.Write([]byte{1, 1})
+			return
+		}
+		c.Write([]byte{1, 0})
+	}
+
+	// Request: version, command, reserved, address type, address, port.
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		var n [1]byte
+		io.ReadFull(r, n[:])
+		name := make([]byte, n[0])
+		io.ReadFull(r, name)
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	io.ReadFull(r, port)
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	s.mu.Lock()
+	s.conns++
+	s.mu.Unlock()
+
+	go io.Copy(target, r)
+	io.Copy(c, target)
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	proxy := newSOCKS5Server(t, "user", "secret")
+
+	c := client.New(client.WithSOCKS5Proxy(proxy.ln.Addr().String(), &client.ProxyAuth{Username: "user", Password: "secret"}))
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("expected body %q, got %q", "hello", body)
+	}
+	if n := proxy.Conns(); n != 1 {
+		t.Errorf("expected 1 proxied connection, got %d", n)
+	}
+
+	bad := client.New(client.WithSOCKS5Proxy(proxy.ln.Addr().String(), &client.ProxyAuth{Username: "user", Password: "wrong"}))
+	if _, err := bad.Get(ts.URL); err == nil {
+		t.Error("expected an authentication error")
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/haleyrc/http/internal/ratelimit"
+)
+
+// WithUploadRateLimit returns an Option that sends request bodies no faster
+// than bytesPerSec bytes per second, so a background client, such as one
+// syncing files, does not saturate a shared uplink and slow down interactive
+// traffic. All requests made by the client draw from the same token bucket,
+// which holds a tenth of a second's worth of bytes.
+//
+// The limit is applied beneath WithRetry, so the body of every attempt is
+// paced, including a body rewound with GetBody for a retry or redirect. A
+// body read waiting for the limit fails with the request context's error once
+// the context is done. A bytesPerSec that is not positive leaves uploads
+// unlimited.
+func WithUploadRateLimit(bytesPerSec int64) Option {
+	return func(c *Client) *Client {
+		if bytesPerSec <= 0 {
+			return c
+		}
+		c.attempt = append(c.attempt, layer{name: "upload_rate_limit", mw: func(next http.RoundTripper) http.RoundTripper {
+			// The bucket is created once the middleware is installed, after
+			// every option, so it uses the configured clock.
+			bucket := ratelimit.NewBucket(bytesPerSec, c.clock)
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Body == nil || req.Body == http.NoBody {
+					return next.RoundTrip(req)
+				}
+				ctx := req.Context()
+				limit := func(body io.ReadCloser) io.ReadCloser {
+					return rateLimitedBody{ratelimit.NewReader(ctx, body, bucket), body}
+				}
+
+				req = cloneRequest(req)
+				req.Body = limit(req.Body)
+				if getBody := req.GetBody; getBody != nil {
+					req.GetBody = func() (io.ReadCloser, error) {
+						body, err := getBody()
+						if err != nil {
+							return nil, err
+						}
+						return limit(body), nil
+					}
+				}
+				return next.RoundTrip(req)
+			})
+		}})
+		return c
+	}
+}
+
+// rateLimitedBody is a request body read through a rate-limited reader.
+type rateLimitedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,77 @@
+package client_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestUploadRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer ts.Close()
+
+	// 20KiB at 40KiB/s takes about 0.4s once the initial burst of 4KiB is
+	// spent.
+	c := client.New(client.WithUploadRateLimit(40<<10), client.WithTimeout(5*time.Second))
+	start := time.Now()
+	resp, err := c.Post(ts.URL, "application/octet-stream", bytes.NewReader(make([]byte, 20<<10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	const want = 400 * time.Millisecond
+	if got := time.Since(start); got < want*3/4 || got > want*3 {
+		t.Errorf("expected the upload to take about %s, took %s", want, got)
+	}
+}
+
+func TestUploadRateLimitRetry(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789"), 1000)
+
+	var (
+		mu       sync.Mutex
+		received [][]byte
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, b)
+		if len(received) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	// Three attempts of 10kB at 50kB/s take about 0.5s after the first burst.
+	c := client.New(client.WithUploadRateLimit(50_000), client.WithRetry(fastRetry))
+	req, _ := http.NewRequest("PUT", ts.URL, bytes.NewReader(body))
+	start := time.Now()
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	elapsed := time.Since(start)
+
+	if resp.StatusCode != http.StatusOK || len(received) != 3 {
+		t.Fatalf("expected success on the third attempt, got %d after %d", resp.StatusCode, len(received))
+	}
+	for i, b := range received {
+		if !bytes.Equal(b, body) {
+			t.Errorf("attempt %d: expected the whole body, got %d bytes", i+1, len(b))
+		}
+	}
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("expected every attempt to be paced, took %s", elapsed)
+	}
+}
@@ -0,0 +1,73 @@
+package client_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+// redirector returns a server that redirects /n to /n-1 until it reaches /0.
+func redirector(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Path[1:])
+		if n > 0 {
+			http.Redirect(w, r, "/"+strconv.Itoa(n-1), http.StatusFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestMaxRedirectsDisabled(t *testing.T) {
+	ts := redirector(t)
+	resp, err := client.New(client.WithMaxRedirects(0)).Get(ts.URL + "/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("expected status %d, got %d", http.StatusFound, resp.StatusCode)
+	}
+}
+
+func TestMaxRedirectsLimited(t *testing.T) {
+	ts := redirector(t)
+	c := client.New(client.WithMaxRedirects(3))
+
+	resp, err := c.Get(ts.URL + "/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 3 redirects to be followed, got status %d", resp.StatusCode)
+	}
+
+	if _, err := c.Get(ts.URL + "/4"); !errors.Is(err, client.ErrTooManyRedirects) {
+		t.Errorf("expected error %v, got %v", client.ErrTooManyRedirects, err)
+	}
+}
+
+func TestMaxRedirectsDefault(t *testing.T) {
+	ts := redirector(t)
+
+	resp, err := client.New().Get(ts.URL + "/9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, err := client.New().Get(ts.URL + "/10"); err == nil {
+		t.Error("expected the default limit of 10 requests to be enforced")
+	}
+
+	resp, err = client.New(client.WithMaxRedirects(-1)).Get(ts.URL + "/20")
+	if err != nil {
+		t.Fatalf("expected unlimited redirects, got %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// DefaultRelayBufferSize is the buffer size Relay uses if bufSize is not
+// positive.
+const DefaultRelayBufferSize = 32 << 10
+
+// Relay copies the body of resp to dst through a single buffer of bufSize
+// bytes, and closes the body. Each chunk is written to dst before the next is
+// read, so a slow dst, such as the ResponseWriter of a slow downstream client,
+// slows the read from upstream instead of the data piling up in memory. If dst
+// is an http.Flusher, it is flushed after every write so the data is not held
+// in its own buffer either.
+//
+// Relay stops when the body is exhausted, when reading or writing fails, or
+// when ctx is done, returning the number of bytes written to dst. If ctx is
+// done, the body is closed to abort a pending read, and the context's cause is
+// returned.
+func Relay(ctx context.Context, dst io.Writer, resp *http.Response, bufSize int) (int64, error) {
+	defer resp.Body.Close()
+	if bufSize <= 0 {
+		bufSize = DefaultRelayBufferSize
+	}
+	stop := context.AfterFunc(ctx, func() { resp.Body.Close() })
+	defer stop()
+
+	flusher, _ := dst.(http.Flusher)
+	buf := make([]byte, bufSize)
+	var written int64
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			if err := ctx.Err(); err != nil {
+				return written, context.Cause(ctx)
+			}
+			m, werr := dst.Write(buf[:n])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+			if m < n {
+				return written, io.ErrShortWrite
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			if ctx.Err() != nil {
+				return written, context.Cause(ctx)
+			}
+			return written, rerr
+		}
+	}
+}
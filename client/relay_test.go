@@ -0,0 +1,89 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+// slowWriter records the size of each write, sleeping before accepting it.
+type slowWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes []int
+	delay  time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, len(p))
+	return w.buf.Write(p)
+}
+
+func TestRelay(t *testing.T) {
+	body := strings.Repeat("x", 10<<10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer ts.Close()
+
+	resp, err := client.New().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := &slowWriter{delay: time.Millisecond}
+	n, err := client.Relay(context.Background(), dst, resp, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(body)) || dst.buf.String() != body {
+		t.Errorf("expected %d bytes relayed intact, got %d", len(body), n)
+	}
+	for i, size := range dst.writes {
+		if size > 1024 {
+			t.Fatalf("write %d: expected at most 1024 bytes, got %d", i, size)
+		}
+	}
+}
+
+func TestRelayContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	resp, err := client.New().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dst := &slowWriter{}
+	time.AfterFunc(20*time.Millisecond, cancel)
+	n, err := client.Relay(ctx, dst, resp, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+	if n != int64(len("partial")) {
+		t.Errorf("expected the bytes before cancellation to be relayed, got %d", n)
+	}
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+// RetryPolicy configures how WithRetry retries failed requests.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent, including
+	// the first. If zero, 3 is used.
+	MaxAttempts int
+
+	// MinBackoff and MaxBackoff bound the delay before each retry. The delay
+	// grows exponentially from MinBackoff with full jitter, and is capped at
+	// MaxBackoff. If zero, 100ms and 2s are used.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// ShouldRetry reports whether a request should be retried after receiving
+	// resp or err from an attempt. If nil, DefaultShouldRetry is used.
+	ShouldRetry func(req *http.Request, resp *http.Response, err error) bool
+}
+
+// DefaultShouldRetry retries idempotent requests that failed with a transport
+// error or a 429, 502, 503 or 504 response. A request is considered idempotent
+// if its method is idempotent or it has an Idempotency-Key header.
+func DefaultShouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if !isIdempotent(req) {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// WithRetry returns an Option that retries failed requests according to p.
+// Retries happen beneath any middleware added with WithMiddleware, so
+// middleware sees a single round trip however many attempts it took.
+//
+// Requests with a body are only retried if the body can be rewound with
+// GetBody, which http.NewRequest sets for common body types. A Retry-After
+// header on a 429 or 503 response is honored, up to MaxBackoff.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) *Client {
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = 3
+		}
+		if p.MinBackoff == 0 {
+			p.MinBackoff = 100 * time.Millisecond
+		}
+		if p.MaxBackoff == 0 {
+			p.MaxBackoff = 2 * time.Second
+		}
+		if p.ShouldRetry == nil {
+			p.ShouldRetry = DefaultShouldRetry
+		}
+		c.retry = &p
+		return c
+	}
+}
+
+// RetryBudgetWindow is the sliding window over which WithRetryBudget measures
+// the ratio of retries to requests.
+const RetryBudgetWindow = 10 * time.Second
+
+// RetryBudgetMinimum is the number of retries permitted in every
+// RetryBudgetWindow regardless of the ratio, so that a client making few
+// requests can still retry them.
+const RetryBudgetMinimum = 10
+
+// WithRetryBudget returns an Option that limits the retries made by WithRetry
+// to ratio of the requests made by the client, such as 0.1 for 10%, over a
+// sliding RetryBudgetWindow. Once the budget is spent, failed attempts are
+// returned to the caller even if the retry policy would retry them, which
+// keeps retries from multiplying the load on an upstream that is already
+// struggling.
+//
+// The budget is shared by all requests made with the client, and has no effect
+// without WithRetry.
+func WithRetryBudget(ratio float64) Option {
+	return func(c *Client) *Client {
+		c.retryBudget = &retryBudget{ratio: ratio}
+		return c
+	}
+}
+
+// retryBudget counts requests and retries in one-second buckets covering the
+// last RetryBudgetWindow.
+type retryBudget struct {
+	ratio float64
+
+	mu      sync.Mutex
+	buckets [RetryBudgetWindow / time.Second]struct {
+		sec               int64
+		requests, retries int
+	}
+}
+
+// bucket returns the bucket for now, resetting it if it was last used in an
+// earlier window. mu must be held.
+func (b *retryBudget) bucket(now time.Time) (requests, retries *int) {
+	sec := now.Unix()
+	bk := &b.buckets[sec%int64(len(b.buckets))]
+	if bk.sec != sec {
+		bk.sec, bk.requests, bk.retries = sec, 0, 0
+	}
+	return &bk.requests, &bk.retries
+}
+
+func (b *retryBudget) recordRequest(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	requests, _ := b.bucket(now)
+	*requests++
+}
+
+// tryRetry reports whether a retry is within budget, and records it if so.
+func (b *retryBudget) tryRetry(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var requests, retries int
+	oldest := now.Unix() - int64(len(b.buckets)) + 1
+	for _, bk := range b.buckets {
+		if bk.sec >= oldest {
+			requests += bk.requests
+			retries += bk.retries
+		}
+	}
+	if retries >= RetryBudgetMinimum && float64(retries+1) > b.ratio*float64(requests) {
+		return false
+	}
+	_, r := b.bucket(now)
+	*r++
+	return true
+}
+
+// retrier is the RoundTripper installed by WithRetry.
+type retrier struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	budget *retryBudget
+	clock  clock.Clock
+}
+
+func (rt *retrier) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt.budget != nil {
+		rt.budget.recordRequest(rt.clock.Now())
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := rt.next.RoundTrip(req)
+		if attempt >= rt.policy.MaxAttempts || !rt.policy.ShouldRetry(req, resp, err) {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+		if rt.budget != nil && !rt.budget.tryRetry(rt.clock.Now()) {
+			return resp, err
+		}
+
+		delay := rt.backoff(attempt, resp)
+		if resp != nil {
+			drainAndClose(resp.Body)
+		}
+		if err := rt.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = cloneRequest(req)
+			req.Body = body
+		}
+	}
+}
+
+// backoff returns the delay before the retry following the given attempt.
+func (rt *retrier) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, rt.policy.MaxBackoff)
+		}
+	}
+	ceiling := rt.policy.MinBackoff << (attempt - 1)
+	if ceiling <= 0 || ceiling > rt.policy.MaxBackoff {
+		ceiling = rt.policy.MaxBackoff
+	}
+	return rand.N(ceiling + 1)
+}
+
+func (rt *retrier) sleep(ctx context.Context, d time.Duration) error {
+	t := rt.clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,80 @@
+package client_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+// flaky returns a server that responds 503 to the first failures requests and
+// 200 after that, along with a count of the requests it has received.
+func flaky(t *testing.T, failures int64) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var hits atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &hits
+}
+
+var fastRetry = client.RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func TestRetry(t *testing.T) {
+	ts, hits := flaky(t, 2)
+
+	c := client.New(client.WithRetry(fastRetry))
+	resp, err := c.Post(ts.URL, "text/plain", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected POST not to be retried, got status %d", resp.StatusCode)
+	}
+	hits.Store(0)
+
+	resp, err = c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	ts, hits := flaky(t, 1<<30)
+
+	policy := fastRetry
+	policy.MaxAttempts = 2
+	c := client.New(client.WithRetry(policy), client.WithRetryBudget(0.1))
+
+	const requests = 50
+	for range requests {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// Without a budget every request would be retried once. With it, only
+	// the minimum number of retries is allowed, since 10% of 50 requests is
+	// fewer than that.
+	want := int64(requests + client.RetryBudgetMinimum)
+	if got := hits.Load(); got != want {
+		t.Errorf("expected %d attempts, got %d", want, got)
+	}
+}
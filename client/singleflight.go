@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// WithSingleflight returns an Option that coalesces identical GET and HEAD
+// requests that are in flight at the same time, so that only the first is sent
+// and every caller receives a copy of its response. This cuts the load that a
+// burst of requests for the same hot resource puts on the upstream.
+//
+// Requests are identical if they have the same method, URL and headers, so a
+// response is only ever shared between callers that asked for exactly the
+// same thing. Requests with a body, a Cookie header or a Range header are
+// never coalesced, since their responses are specific to one caller or one
+// part of a resource.
+//
+// A response that no other caller is waiting for is returned as is, so a
+// request sent alone streams its body as usual. Only once a second caller has
+// joined is the shared response body read into memory in full; each caller
+// then gets its own reader over it and its own copy of the header, so callers
+// can read and close their response independently. Coalesced requests are
+// sent with the first caller's context: if it is cancelled, the others receive
+// the error too, though each may still stop waiting when its own context is
+// done.
+func WithSingleflight() Option {
+	return func(c *Client) *Client {
+		g := &flightGroup{calls: make(map[string]*flight)}
+		return withNamedMiddleware("singleflight", func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				key, ok := flightKey(req)
+				if !ok {
+					return next.RoundTrip(req)
+				}
+				return g.do(key, req, next)
+			})
+		})(c)
+	}
+}
+
+// errFlightAborted is returned to the callers waiting on a coalesced request
+// when the request that was sent panics.
+var errFlightAborted = errors.New("client: coalesced request was aborted")
+
+// flightKey returns the key identifying requests identical to req, and false
+// if req must not be coalesced.
+func flightKey(req *http.Request) (string, bool) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return "", false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return "", false
+	}
+	if req.Header.Get("Cookie") != "" || req.Header.Get("Range") != "" {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte(' ')
+	b.WriteString(req.URL.String())
+	for _, k := range slices.Sorted(maps.Keys(req.Header)) {
+		b.WriteByte('\n')
+		b.WriteString(k)
+		for _, v := range req.Header[k] {
+			b.WriteByte('\x00')
+			b.WriteString(v)
+		}
+	}
+	return b.String(), true
+}
+
+// flightGroup tracks the coalesced requests in flight.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a request in flight, and its buffered result once done is closed.
+type flight struct {
+	done chan struct{}
+	dups int
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+func (g *flightGroup) do(key string, req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	g.mu.Lock()
+	if f, ok := g.calls[key]; ok {
+		f.dups++
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.result(req)
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	f := &flight{done: make(chan struct{}), err: errFlightAborted}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	// Release the callers waiting on f even if the round trip panics.
+	defer func() {
+		g.mu.Lock()
+		if g.calls[key] == f {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	resp, err := next.RoundTrip(req)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	dups := f.dups
+	g.mu.Unlock()
+
+	if err != nil || dups == 0 {
+		f.err = err
+		return resp, err
+	}
+	f.resp = resp
+	f.body, f.err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return f.result(req)
+}
+
+// result returns a copy of the flight's response for req.
+func (f *flight) result(req *http.Request) (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := *f.resp
+	resp.Header = f.resp.Header.Clone()
+	resp.Trailer = f.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(f.body))
+	resp.Request = req
+	return &resp, nil
+}
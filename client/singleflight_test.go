@@ -0,0 +1,234 @@
+package client_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestSingleflight(t *testing.T) {
+	const n = 10
+	var calls atomic.Int32
+	var arrived sync.WaitGroup
+	arrived.Add(n)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("X-Widget", "42")
+		w.Write([]byte("widget"))
+	}))
+	defer ts.Close()
+
+	// Every caller has entered the round trip before the upstream responds.
+	c := client.New(
+		client.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				arrived.Done()
+				return next.RoundTrip(req)
+			})
+		}),
+		client.WithSingleflight(),
+	)
+	go func() {
+		arrived.Wait()
+		time.Sleep(10 * time.Millisecond) // let the last caller join the flight
+		close(release)
+	}()
+
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(ts.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			resp.Header.Set("X-Widget", "mine")
+			body, _ := io.ReadAll(resp.Body)
+			bodies[i] = string(body)
+		}()
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 upstream call, got %d", got)
+	}
+	for i, body := range bodies {
+		if body != "widget" {
+			t.Errorf("caller %d: expected body %q, got %q", i, "widget", body)
+		}
+	}
+}
+
+func TestSingleflightSequential(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer ts.Close()
+
+	// Requests that are not in flight at the same time are all sent.
+	c := client.New(client.WithSingleflight())
+	for range 3 {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 upstream calls, got %d", got)
+	}
+}
+
+func TestSingleflightStreamsAlone(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	// A request with no duplicates returns before the body is complete.
+	c := client.New(client.WithSingleflight())
+	done := make(chan string)
+	go func() {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Error(err)
+			done <- ""
+			return
+		}
+		defer resp.Body.Close()
+		buf := make([]byte, len("first"))
+		io.ReadFull(resp.Body, buf)
+		done <- string(buf)
+	}()
+	select {
+	case got := <-done:
+		if got != "first" {
+			t.Errorf("expected to read %q, got %q", "first", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("response was buffered instead of streamed")
+	}
+}
+
+func TestSingleflightDistinctRequests(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		headers [2]http.Header
+	}{
+		{"api key", [2]http.Header{{"X-Api-Key": {"alice"}}, {"X-Api-Key": {"bob"}}}},
+		{"accept", [2]http.Header{{"Accept": {"text/csv"}}, {"Accept": {"application/json"}}}},
+		{"cookie", [2]http.Header{{"Cookie": {"session=1"}}, {"Cookie": {"session=1"}}}},
+		{"range", [2]http.Header{{"Range": {"bytes=0-9"}}, {"Range": {"bytes=0-9"}}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var arrived sync.WaitGroup
+			arrived.Add(2)
+			release := make(chan struct{})
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				arrived.Done()
+				<-release
+			}))
+			defer ts.Close()
+
+			c := client.New(client.WithSingleflight())
+			var wg sync.WaitGroup
+			for _, hdr := range tt.headers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req, _ := http.NewRequest("GET", ts.URL, nil)
+					req.Header = hdr
+					resp, err := c.Do(req)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					resp.Body.Close()
+				}()
+			}
+
+			both := make(chan struct{})
+			go func() {
+				arrived.Wait()
+				close(both)
+			}()
+			select {
+			case <-both:
+			case <-time.After(5 * time.Second):
+				t.Error("expected both requests to be sent")
+			}
+			close(release)
+			wg.Wait()
+		})
+	}
+}
+
+func TestSingleflightLeaderPanic(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	c := client.New(
+		client.WithPanicPropagation(),
+		client.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				arrived.Done()
+				return next.RoundTrip(req)
+			})
+		}),
+		client.WithSingleflight(),
+		client.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return client.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				arrived.Wait()
+				time.Sleep(10 * time.Millisecond) // let the follower join the flight
+				panic("boom")
+			})
+		}),
+	)
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			defer func() {
+				if v := recover(); v != nil {
+					errs <- nil
+				}
+			}()
+			_, err := c.Get(ts.URL)
+			errs <- err
+		}()
+	}
+
+	var failed int
+	for range 2 {
+		select {
+		case err := <-errs:
+			if err != nil {
+				failed++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a caller was left waiting after the request panicked")
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected the follower to get an error, got %d errors", failed)
+	}
+}
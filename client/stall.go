@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrStalled is returned when reading a response body if no data arrived for
+// longer than the stall timeout set with WithStallTimeout.
+var ErrStalled = errors.New("client: response body stalled")
+
+// WithStallTimeout returns an Option that fails a response body read with
+// ErrStalled if it waits longer than d for data. Unlike WithTimeout, which
+// bounds the whole request including reading the body, this only bounds each
+// wait, so a long download that keeps making progress is never cut off, but
+// one that hangs part way through is. Time the caller spends between reads
+// does not count towards d.
+//
+// If the client uses an *http.Transport without a ResponseHeaderTimeout, d
+// also bounds the wait for the response headers once the request has been
+// sent.
+func WithStallTimeout(d time.Duration) Option {
+	return func(c *Client) *Client {
+		if t := c.transport(); t != nil && t.ResponseHeaderTimeout == 0 {
+			t.ResponseHeaderTimeout = d
+		}
+		return withNamedMiddleware("stall_timeout", func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				resp, err := next.RoundTrip(req)
+				if err != nil {
+					return resp, err
+				}
+				resp.Body = newStallReader(resp.Body, d, c.clock)
+				return resp, nil
+			})
+		})(c)
+	}
+}
+
+// stallReader closes the body it wraps, failing the pending read, if a read
+// blocks for longer than the timeout.
+type stallReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	clock   Clock
+	timer   Timer
+	done    chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	reading  bool
+	deadline time.Time
+	stalled  bool
+}
+
+func newStallReader(body io.ReadCloser, d time.Duration, clk Clock) *stallReader {
+	sr := &stallReader{body: body, timeout: d, clock: clk, timer: clk.NewTimer(d), done: make(chan struct{})}
+	sr.timer.Stop()
+	go sr.watch()
+	return sr
+}
+
+// watch closes the body when the timer fires during a read. A firing from
+// before the current read's deadline was meant for an earlier read that
+// finished just as the timer fired, and is ignored.
+func (sr *stallReader) watch() {
+	for {
+		select {
+		case at := <-sr.timer.C():
+			sr.mu.Lock()
+			if sr.reading && !at.Before(sr.deadline) {
+				sr.stalled = true
+				sr.body.Close()
+			}
+			sr.mu.Unlock()
+		case <-sr.done:
+			return
+		}
+	}
+}
+
+func (sr *stallReader) Read(p []byte) (int, error) {
+	sr.mu.Lock()
+	if sr.stalled {
+		sr.mu.Unlock()
+		return 0, ErrStalled
+	}
+	sr.reading = true
+	sr.deadline = sr.clock.Now().Add(sr.timeout)
+	sr.timer.Reset(sr.timeout)
+	sr.mu.Unlock()
+
+	n, err := sr.body.Read(p)
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.reading = false
+	sr.timer.Stop()
+	if sr.stalled {
+		return n, ErrStalled
+	}
+	return n, err
+}
+
+func (sr *stallReader) Close() error {
+	sr.once.Do(func() {
+		sr.timer.Stop()
+		close(sr.done)
+	})
+	return sr.body.Close()
+}
@@ -0,0 +1,128 @@
+package client_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/client"
+	"github.com/haleyrc/http/internal/clock"
+)
+
+// stallingServer writes "partial" and then hangs until the test ends.
+func stallingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		ts.Close()
+	})
+	return ts
+}
+
+func TestStallTimeout(t *testing.T) {
+	ts := stallingServer(t)
+	clk := clock.NewFake(time.Now())
+	c := client.New(client.WithClock(clk), client.WithStallTimeout(time.Second))
+
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	type result struct {
+		body []byte
+		err  error
+	}
+	done := make(chan result)
+	go func() {
+		body, err := io.ReadAll(resp.Body)
+		done <- result{body, err}
+	}()
+
+	// Wait for the read of the stalled remainder to start its timer.
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(time.Second)
+
+	res := <-done
+	if !errors.Is(res.err, client.ErrStalled) {
+		t.Errorf("expected error %v, got %v", client.ErrStalled, res.err)
+	}
+	if string(res.body) != "partial" {
+		t.Errorf("expected the data before the stall, got %q", res.body)
+	}
+}
+
+func TestStallTimeoutProgress(t *testing.T) {
+	next := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for range 5 {
+			<-next
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer ts.Close()
+
+	clk := clock.NewFake(time.Now())
+	c := client.New(client.WithClock(clk), client.WithStallTimeout(time.Second))
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Each chunk arrives within the stall timeout, even though the whole body
+	// takes longer than it.
+	for i := range 5 {
+		done := make(chan error, 1)
+		go func() {
+			_, err := io.ReadFull(resp.Body, make([]byte, 5))
+			done <- err
+		}()
+		for clk.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clk.Advance(900 * time.Millisecond)
+		next <- struct{}{}
+		if err := <-done; err != nil {
+			t.Fatalf("chunk %d: expected no error, got %v", i, err)
+		}
+	}
+	if rest, err := io.ReadAll(resp.Body); err != nil || len(rest) != 0 {
+		t.Errorf("expected the end of the body, got %q (err %v)", rest, err)
+	}
+}
+
+func TestStallTimeoutHeaders(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	c := client.New(client.WithStallTimeout(20 * time.Millisecond))
+	if _, err := c.Get(ts.URL); err == nil {
+		t.Error("expected an error waiting for headers")
+	}
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+// ConnReuseRecorder receives a report, for every request, of whether the
+// request was sent on a pooled connection. Implementations must be safe for
+// concurrent use.
+type ConnReuseRecorder interface {
+	RecordConnReuse(reused bool)
+}
+
+// WithConnMetrics returns an Option that reports to rec whether each request
+// reused a pooled connection. A high proportion of new connections suggests
+// keep-alives are not working, which hurts latency and can exhaust ports.
+//
+// The hook is installed with httptrace, and runs alongside any trace already
+// present in the request's context.
+func WithConnMetrics(rec ConnReuseRecorder) Option {
+	return withTrace("conn_metrics", func(req *http.Request) *httptrace.ClientTrace {
+		return &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				rec.RecordConnReuse(info.Reused)
+			},
+		}
+	})
+}
+
+// withTrace returns an Option that installs the trace returned by newTrace on
+// every request, listed as name by Describe. httptrace composes it with any
+// trace already in the request's context, so the caller's hooks still fire.
+func withTrace(name string, newTrace func(req *http.Request) *httptrace.ClientTrace) Option {
+	return withNamedMiddleware(name, func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := httptrace.WithClientTrace(req.Context(), newTrace(req))
+			return next.RoundTrip(req.WithContext(ctx))
+		})
+	})
+}
+
+// ComposeTrace returns a trace whose hooks call the corresponding hooks of
+// each of traces, in order, skipping nil traces and hooks. This lets several
+// sets of hooks, such as a caller's own and those of a metrics library, be
+// installed as one trace with a known order.
+//
+// Hooks that return an error, such as Got1xxResponse, stop at the first hook
+// that returns a non-nil error and return it.
+//
+// Installing a trace with httptrace.WithClientTrace already composes it with
+// any trace in the context, which is how the options of this package avoid
+// clobbering each other and the caller's trace, but hooks added later fire
+// first. ComposeTrace is for when the order matters, or when a single trace
+// is needed.
+func ComposeTrace(traces ...*httptrace.ClientTrace) *httptrace.ClientTrace {
+	composed := &httptrace.ClientTrace{}
+	out := reflect.ValueOf(composed).Elem()
+	for i := 0; i < out.NumField(); i++ {
+		field := out.Field(i)
+		if field.Kind() != reflect.Func {
+			continue
+		}
+		var hooks []reflect.Value
+		for _, t := range traces {
+			if t == nil {
+				continue
+			}
+			if hook := reflect.ValueOf(t).Elem().Field(i); !hook.IsNil() {
+				hooks = append(hooks, hook)
+			}
+		}
+		switch len(hooks) {
+		case 0:
+			continue
+		case 1:
+			field.Set(hooks[0])
+			continue
+		}
+		field.Set(reflect.MakeFunc(field.Type(), func(args []reflect.Value) []reflect.Value {
+			var results []reflect.Value
+			for _, hook := range hooks {
+				results = hook.Call(args)
+				if n := len(results); n > 0 && results[n-1].Type() == errorType && !results[n-1].IsNil() {
+					break
+				}
+			}
+			return results
+		}))
+	}
+	return composed
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// Timings holds the duration of each phase of a request. Phases that did not
+// happen, such as DNS lookup and connecting on a reused connection, or the TLS
+// handshake for plain HTTP, are zero.
+type Timings struct {
+	// DNSLookup is the time spent resolving the host name.
+	DNSLookup time.Duration
+
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+
+	// TLSHandshake is the time spent on the TLS handshake.
+	TLSHandshake time.Duration
+
+	// TimeToFirstByte is the time from the start of the request until the
+	// first byte of the response was received.
+	TimeToFirstByte time.Duration
+
+	// Total is the time from the start of the request until the response body
+	// was fully read or closed, or the request failed.
+	Total time.Duration
+
+	// Reused reports whether the request was sent on a pooled connection.
+	Reused bool
+}
+
+// WithTimingTrace returns an Option that measures the phases of every request
+// and passes them to fn once the request completes: when the response body has
+// been read to the end or closed, or when the request fails.
+//
+// The hooks are installed with httptrace, and run alongside any trace already
+// present in the request's context.
+func WithTimingTrace(fn func(Timings)) Option {
+	return func(c *Client) *Client {
+		return withNamedMiddleware("timing_trace", func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				tt := &timingTrace{clock: c.clock, start: c.clock.Now()}
+				ctx := httptrace.WithClientTrace(req.Context(), tt.trace())
+				resp, err := next.RoundTrip(req.WithContext(ctx))
+				if err != nil {
+					fn(tt.done())
+					return nil, err
+				}
+				resp.Body = &timingBody{ReadCloser: resp.Body, done: func() { fn(tt.done()) }}
+				return resp, nil
+			})
+		})(c)
+	}
+}
+
+// timingTrace accumulates the times at which each phase of a request started
+// and finished. Hooks can be called from multiple goroutines, so all access is
+// guarded by mu.
+type timingTrace struct {
+	clock clock.Clock
+	start time.Time
+
+	mu                  sync.Mutex
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	firstByte           time.Time
+	reused              bool
+}
+
+func (tt *timingTrace) record(t *time.Time) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	*t = tt.clock.Now()
+}
+
+func (tt *timingTrace) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { tt.record(&tt.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { tt.record(&tt.dnsDone) },
+		ConnectStart: func(string, string) {
+			tt.mu.Lock()
+			defer tt.mu.Unlock()
+			// With multiple addresses, dials may race; measure from the
+			// first.
+			if tt.connStart.IsZero() {
+				tt.connStart = tt.clock.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { tt.record(&tt.connDone) },
+		TLSHandshakeStart:    func() { tt.record(&tt.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tt.record(&tt.tlsDone) },
+		GotFirstResponseByte: func() { tt.record(&tt.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			tt.mu.Lock()
+			defer tt.mu.Unlock()
+			tt.reused = info.Reused
+		},
+	}
+}
+
+// done returns the final Timings, treating now as the end of the request.
+func (tt *timingTrace) done() Timings {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return Timings{
+		DNSLookup:       between(tt.dnsStart, tt.dnsDone),
+		Connect:         between(tt.connStart, tt.connDone),
+		TLSHandshake:    between(tt.tlsStart, tt.tlsDone),
+		TimeToFirstByte: between(tt.start, tt.firstByte),
+		Total:           tt.clock.Now().Sub(tt.start),
+		Reused:          tt.reused,
+	}
+}
+
+// between returns the duration from start to end, or zero if either is unset.
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// timingBody calls done exactly once, when the body is read to the end or
+// closed, whichever happens first.
+type timingBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *timingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *timingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
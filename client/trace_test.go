@@ -0,0 +1,166 @@
+package client_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+type reuseRecorder struct {
+	mu     sync.Mutex
+	reused []bool
+}
+
+func (r *reuseRecorder) RecordConnReuse(reused bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reused = append(r.reused, reused)
+}
+
+func TestConnMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	rec := new(reuseRecorder)
+	c := client.New(client.WithConnMetrics(rec))
+
+	var callerHooks int
+	for i := 0; i < 2; i++ {
+		trace := &httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) { callerHooks++ }}
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if len(rec.reused) != 2 || rec.reused[0] || !rec.reused[1] {
+		t.Errorf("expected reuse [false true], got %v", rec.reused)
+	}
+	if callerHooks != 2 {
+		t.Errorf("expected the caller's trace to fire twice, got %d", callerHooks)
+	}
+}
+
+func TestTimingTrace(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	var timings []client.Timings
+	c := client.New(
+		client.WithTransport(ts.Client().Transport.(*http.Transport).Clone()),
+		client.WithTimingTrace(func(tm client.Timings) { timings = append(timings, tm) }),
+	)
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if len(timings) != 2 {
+		t.Fatalf("expected 2 timings, got %d", len(timings))
+	}
+
+	first, second := timings[0], timings[1]
+	if first.Reused || first.Connect == 0 || first.TLSHandshake == 0 {
+		t.Errorf("expected a new connection with connect and TLS times, got %+v", first)
+	}
+	if first.TimeToFirstByte == 0 || first.Total < first.TimeToFirstByte {
+		t.Errorf("expected 0 < time to first byte <= total, got %+v", first)
+	}
+	if !second.Reused || second.DNSLookup != 0 || second.Connect != 0 || second.TLSHandshake != 0 {
+		t.Errorf("expected a reused connection with no setup times, got %+v", second)
+	}
+}
+
+func TestComposeTrace(t *testing.T) {
+	var calls []string
+	hook := func(name string) func(httptrace.GotConnInfo) {
+		return func(httptrace.GotConnInfo) { calls = append(calls, name) }
+	}
+	errStop := errors.New("stop")
+
+	trace := client.ComposeTrace(
+		&httptrace.ClientTrace{GotConn: hook("first")},
+		nil,
+		&httptrace.ClientTrace{},
+		&httptrace.ClientTrace{
+			GotConn: hook("second"),
+			Got1xxResponse: func(int, textproto.MIMEHeader) error {
+				calls = append(calls, "1xx second")
+				return errStop
+			},
+		},
+		&httptrace.ClientTrace{
+			GotConn: hook("third"),
+			Got1xxResponse: func(int, textproto.MIMEHeader) error {
+				calls = append(calls, "1xx third")
+				return nil
+			},
+		},
+	)
+
+	trace.GotConn(httptrace.GotConnInfo{})
+	if err := trace.Got1xxResponse(100, nil); !errors.Is(err, errStop) {
+		t.Errorf("expected the first error to be returned, got %v", err)
+	}
+	if trace.DNSStart != nil {
+		t.Error("expected hooks no trace sets to stay nil")
+	}
+
+	want := "[first second third 1xx second]"
+	if got := fmt.Sprint(calls); got != want {
+		t.Errorf("expected calls %s, got %s", want, got)
+	}
+}
+
+func TestComposeTraceWithClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, name)
+	}
+
+	rec := &reuseRecorder{}
+	c := client.New(client.WithConnMetrics(rec))
+	trace := client.ComposeTrace(
+		&httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) { record("a") }},
+		&httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) { record("b") }},
+	)
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := fmt.Sprint(calls); got != "[a b]" {
+		t.Errorf("expected both of the caller's hooks to fire in order, got %s", got)
+	}
+	if len(rec.reused) != 1 {
+		t.Errorf("expected the client's own hook to fire too, got %v", rec.reused)
+	}
+}
@@ -0,0 +1,121 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when Advance is called. It is safe for
+// concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// NewFake returns a Fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives the fake's time once it has been
+// advanced by at least d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep blocks until the fake has been advanced by at least d.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// NewTimer returns a Timer that fires once the fake has been advanced by at
+// least d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1)}
+	f.schedule(t, d)
+	return t
+}
+
+// Advance moves the fake's time forward by d, firing any timers that expire.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	remaining := f.waiters[:0]
+	for _, t := range f.waiters {
+		if t.deadline.After(f.now) {
+			remaining = append(remaining, t)
+			continue
+		}
+		select {
+		case t.c <- f.now:
+		default:
+		}
+	}
+	f.waiters = remaining
+}
+
+// Waiters returns the number of timers that have not yet fired. Tests can use
+// it to wait until a goroutine has started sleeping before advancing time.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// schedule must be called with f.mu held.
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = f.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.c <- f.now:
+		default:
+		}
+		return
+	}
+	f.waiters = append(f.waiters, t)
+}
+
+// unschedule removes t from the waiters, reporting whether it was pending. It
+// must be called with f.mu held.
+func (f *Fake) unschedule(t *fakeTimer) bool {
+	for i, w := range f.waiters {
+		if w == t {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	f        *Fake
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	active := t.f.unschedule(t)
+	t.f.schedule(t, d)
+	return active
+}
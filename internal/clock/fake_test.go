@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	c := f.After(time.Second)
+	timer := f.NewTimer(2 * time.Second)
+
+	f.Advance(500 * time.Millisecond)
+	select {
+	case <-c:
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	f.Advance(500 * time.Millisecond)
+	select {
+	case got := <-c:
+		if want := start.Add(time.Second); !got.Equal(want) {
+			t.Errorf("expected time %s, got %s", want, got)
+		}
+	default:
+		t.Fatal("expected timer to fire")
+	}
+
+	if !timer.Stop() {
+		t.Error("expected Stop to report an active timer")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+	if n := f.Waiters(); n != 0 {
+		t.Errorf("expected 0 waiters, got %d", n)
+	}
+}
+
+func TestFakeResetUndrained(t *testing.T) {
+	f := NewFake(time.Now())
+	timer := f.NewTimer(0)
+
+	// The channel is still full from the first firing, so this must not
+	// block.
+	done := make(chan struct{})
+	go func() {
+		timer.Reset(0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Reset blocked on an undrained channel")
+	}
+	<-timer.C()
+	f.Advance(time.Second)
+}
@@ -0,0 +1,88 @@
+// Package describe renders the effective configuration of a client or server
+// as text or JSON, for including in bug reports.
+package describe
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Field is a single named configuration value.
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Fields is an ordered list of configuration values.
+type Fields []Field
+
+// Add appends a field.
+func (fs *Fields) Add(name string, value any) {
+	*fs = append(*fs, Field{Name: name, Value: value})
+}
+
+// String renders the fields one per line as "name: value". Durations are
+// printed in their usual form and slices as comma-separated lists.
+func (fs Fields) String() string {
+	var b strings.Builder
+	for _, f := range fs {
+		fmt.Fprintf(&b, "%s: %s\n", f.Name, text(f.Value))
+	}
+	return b.String()
+}
+
+func text(v any) string {
+	switch v := v.(type) {
+	case []string:
+		if len(v) == 0 {
+			return "none"
+		}
+		return strings.Join(v, ", ")
+	case string:
+		if v == "" {
+			return "none"
+		}
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// MarshalJSON renders the fields as a JSON object, in order. Durations are
+// rendered as strings, such as "5s".
+func (fs Fields) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range fs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.Name)
+		b.Write(name)
+		b.WriteByte(':')
+
+		v := f.Value
+		if d, ok := v.(time.Duration); ok {
+			v = d.String()
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// TLSVersion returns the name of a TLS version, such as "TLS 1.2", or
+// "default" for zero.
+func TLSVersion(v uint16) string {
+	if v == 0 {
+		return "default"
+	}
+	return tls.VersionName(v)
+}
@@ -0,0 +1,35 @@
+// Package propagation carries correlation metadata, such as trace and request
+// IDs, from the requests a server receives to the requests its client makes
+// while handling them.
+package propagation
+
+import (
+	"context"
+	"maps"
+	"net/http"
+)
+
+// Bag maps canonical header names to the values to propagate in them.
+type Bag map[string]string
+
+type bagKey struct{}
+
+// NewContext returns a copy of ctx carrying bag, merged over any bag ctx
+// already carries. Keys are canonicalized as header names.
+func NewContext(ctx context.Context, bag Bag) context.Context {
+	merged := maps.Clone(FromContext(ctx))
+	if merged == nil {
+		merged = make(Bag, len(bag))
+	}
+	for k, v := range bag {
+		merged[http.CanonicalHeaderKey(k)] = v
+	}
+	return context.WithValue(ctx, bagKey{}, merged)
+}
+
+// FromContext returns the bag carried by ctx, or nil if there is none. The
+// bag must not be modified.
+func FromContext(ctx context.Context) Bag {
+	bag, _ := ctx.Value(bagKey{}).(Bag)
+	return bag
+}
@@ -0,0 +1,124 @@
+// Package ratelimit provides the token bucket used to throttle the bandwidth
+// of request and response bodies in the client and server packages.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+// Bucket is a token bucket holding up to a tenth of a second's worth of
+// bytes, refilled continuously at the configured rate. It is safe for
+// concurrent use, so one bucket can be shared by everything that should count
+// against the same limit.
+type Bucket struct {
+	rate  float64
+	burst float64
+	clock clock.Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full bucket for bytesPerSec bytes per second, which
+// must be positive.
+func NewBucket(bytesPerSec int64, clk clock.Clock) *Bucket {
+	burst := max(float64(bytesPerSec)/10, 1)
+	return &Bucket{
+		rate:   float64(bytesPerSec),
+		burst:  burst,
+		clock:  clk,
+		tokens: burst,
+		last:   clk.Now(),
+	}
+}
+
+// Wait takes up to n tokens from the bucket, blocking until they are
+// available or ctx is done, and returns how many it took. It takes fewer than
+// n only if n is more than the bucket holds, so callers moving large buffers
+// should call Wait repeatedly.
+func (b *Bucket) Wait(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	take := min(float64(n), b.burst)
+
+	b.mu.Lock()
+	now := b.clock.Now()
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.burst)
+	b.last = now
+	b.tokens -= take
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if wait <= 0 {
+		return int(take), nil
+	}
+	t := b.clock.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return int(take), nil
+	case <-ctx.Done():
+		// Return the tokens that were never used.
+		b.Refund(int(take))
+		return 0, ctx.Err()
+	}
+}
+
+// Refund returns n tokens taken by Wait that went unused, such as when a read
+// returned fewer bytes than were waited for.
+func (b *Bucket) Refund(n int) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.tokens = min(b.tokens+float64(n), b.burst)
+	b.mu.Unlock()
+}
+
+// Reader limits reads from an io.Reader using a Bucket.
+type Reader struct {
+	ctx    context.Context
+	r      io.Reader
+	bucket *Bucket
+}
+
+// NewReader returns a Reader that reads from r no faster than bucket allows,
+// failing with ctx's error once ctx is done.
+func NewReader(ctx context.Context, r io.Reader, bucket *Bucket) *Reader {
+	return &Reader{ctx: ctx, r: r, bucket: bucket}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	take, err := r.bucket.Wait(r.ctx, len(p))
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p[:take])
+	r.bucket.Refund(take - n)
+	return n, err
+}
+
+// Write writes p to w in pieces, waiting on bucket before each one.
+func Write(ctx context.Context, w io.Writer, p []byte, bucket *Bucket) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n, err := bucket.Wait(ctx, len(p))
+		if err != nil {
+			return written, err
+		}
+		n, err = w.Write(p[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+func TestBucket(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	b := NewBucket(1000, clk)
+	ctx := context.Background()
+
+	// The bucket starts with a tenth of a second's worth of tokens.
+	if n, err := b.Wait(ctx, 500); n != 100 || err != nil {
+		t.Fatalf("expected to take the 100 tokens available, got %d %v", n, err)
+	}
+
+	done := make(chan int)
+	go func() {
+		n, _ := b.Wait(ctx, 50)
+		done <- n
+	}()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(49 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("expected Wait to block until 50 tokens were refilled")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clk.Advance(time.Millisecond)
+	if n := <-done; n != 50 {
+		t.Errorf("expected 50 tokens, got %d", n)
+	}
+}
+
+func TestBucketCanceled(t *testing.T) {
+	b := NewBucket(10, clock.Real)
+	b.Wait(context.Background(), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.Wait(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+// shortReader returns at most n bytes from each read.
+type shortReader struct {
+	n int
+}
+
+func (r shortReader) Read(p []byte) (int, error) {
+	return min(len(p), r.n), nil
+}
+
+func TestReaderRefundsShortReads(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	b := NewBucket(1000, clk)
+	r := NewReader(context.Background(), shortReader{n: 10}, b)
+
+	// Each read waits for 50 tokens but gets only 10 bytes. Without refunds,
+	// the 100 tokens the bucket starts with would cover only two reads.
+	done := make(chan error)
+	go func() {
+		buf := make([]byte, 50)
+		for range 5 {
+			if _, err := r.Read(buf); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the unused tokens of short reads to be refunded")
+	}
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format selects the line format written by WithAccessLog.
+type Format int
+
+const (
+	// FormatCombined is the Apache Combined Log Format:
+	//
+	//	127.0.0.1 - alice [10/Oct/2026:13:55:36 +0000] "GET /a HTTP/1.1" 200 2326 "-" "curl/8.0"
+	FormatCombined Format = iota
+
+	// FormatJSON writes one JSON object per line with the fields time,
+	// method, path, status, bytes, duration_ms, remote_ip and request_id.
+	FormatJSON
+
+	// FormatLogfmt writes the same fields as FormatJSON as logfmt key=value
+	// pairs.
+	FormatLogfmt
+)
+
+// WithAccessLogFormat modifies the format of the access log written by
+// WithAccessLog. The default is FormatCombined.
+func WithAccessLogFormat(f Format) Option {
+	return func(s *Server) *Server {
+		s.accessLogFormat = f
+		return s
+	}
+}
+
+// WithAccessLog modifies the server to write a line to w for every request
+// once it has been handled, in the format set with WithAccessLogFormat. Writes
+// to w are serialized, and each line is written with a single call to Write.
+//
+// If w is nil, each request is instead logged at info level to the logger
+// derived for it by WithRequestLogger, which already carries the method, path
+// and request ID, with the status, bytes, duration_ms, remote_ip and, if the
+// request was routed by an http.ServeMux, route added. The format is ignored,
+// and without WithRequestLogger nothing is logged.
+func WithAccessLog(w io.Writer) Option {
+	return func(s *Server) *Server {
+		var mu sync.Mutex
+		s.use("access_log", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				if w == nil {
+					s.logAccess(next, rw, r)
+					return
+				}
+				start := s.clock.Now()
+				sw := &statusWriter{ResponseWriter: rw}
+				next.ServeHTTP(sw, r)
+
+				e := accessLogEntry{
+					time:      start,
+					method:    r.Method,
+					path:      r.URL.RequestURI(),
+					proto:     r.Proto,
+					status:    sw.Status(),
+					bytes:     sw.bytes,
+					duration:  s.clock.Now().Sub(start),
+					remoteIP:  remoteIP(r),
+					requestID: requestID(r),
+					referer:   r.Referer(),
+					userAgent: r.UserAgent(),
+				}
+				e.user, _, _ = r.BasicAuth()
+				if e.requestID == "" {
+					// RequestID, installed after the access log, only
+					// leaves the ID it generated on the response.
+					e.requestID = rw.Header().Get(RequestIDHeader)
+				}
+
+				line := e.format(s.accessLogFormat)
+				mu.Lock()
+				defer mu.Unlock()
+				io.WriteString(w, line)
+			})
+		})
+		return s
+	}
+}
+
+// logAccess serves r with next and logs it to the request-scoped logger, for
+// WithAccessLog without a writer.
+func (s *Server) logAccess(next http.Handler, rw http.ResponseWriter, r *http.Request) {
+	r, route := withRoute(r)
+	r, slot := withLoggerSlot(r)
+	start := s.clock.Now()
+	sw := &statusWriter{ResponseWriter: rw}
+	next.ServeHTTP(sw, r)
+
+	l := *slot
+	if l == nil {
+		// WithRequestLogger, if any, ran before the access log.
+		l = LoggerFromContext(r.Context())
+	}
+	elapsed := s.clock.Now().Sub(start)
+	kv := []any{
+		"status", sw.Status(),
+		"bytes", sw.bytes,
+		"duration_ms", float64(elapsed.Microseconds()) / 1000,
+		"remote_ip", remoteIP(r),
+	}
+	if *route != "" {
+		kv = append(kv, "route", *route)
+	}
+	l.Info("request", kv...)
+}
+
+// statusWriter records the status code and number of body bytes written
+// through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush flushes the underlying writer, if it supports flushing. Flushing
+// before writing the header sends a 200.
+func (w *statusWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code sent, which is 200 if the handler wrote
+// nothing at all.
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// remoteIP returns the IP address of the client that sent r.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type accessLogEntry struct {
+	time      time.Time
+	method    string
+	path      string
+	proto     string
+	status    int
+	bytes     int64
+	duration  time.Duration
+	remoteIP  string
+	requestID string
+	user      string
+	referer   string
+	userAgent string
+}
+
+// format returns the entry as a line, including the trailing newline.
+func (e accessLogEntry) format(f Format) string {
+	switch f {
+	case FormatJSON:
+		b, _ := json.Marshal(struct {
+			Time       string  `json:"time"`
+			Method     string  `json:"method"`
+			Path       string  `json:"path"`
+			Status     int     `json:"status"`
+			Bytes      int64   `json:"bytes"`
+			DurationMS float64 `json:"duration_ms"`
+			RemoteIP   string  `json:"remote_ip"`
+			RequestID  string  `json:"request_id,omitempty"`
+		}{
+			e.time.Format(time.RFC3339Nano), e.method, e.path, e.status, e.bytes,
+			e.durationMS(), e.remoteIP, e.requestID,
+		})
+		return string(b) + "\n"
+
+	case FormatLogfmt:
+		var b strings.Builder
+		kv := func(k, v string) {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(k)
+			b.WriteByte('=')
+			b.WriteString(logfmtValue(v))
+		}
+		kv("time", e.time.Format(time.RFC3339Nano))
+		kv("method", e.method)
+		kv("path", e.path)
+		kv("status", strconv.Itoa(e.status))
+		kv("bytes", strconv.FormatInt(e.bytes, 10))
+		kv("duration_ms", strconv.FormatFloat(e.durationMS(), 'f', -1, 64))
+		kv("remote_ip", e.remoteIP)
+		if e.requestID != "" {
+			kv("request_id", e.requestID)
+		}
+		b.WriteByte('\n')
+		return b.String()
+	}
+
+	bytes := "-"
+	if e.bytes > 0 {
+		bytes = strconv.FormatInt(e.bytes, 10)
+	}
+	return fmt.Sprintf("%s - %s [%s] %s %d %s %s %s\n",
+		e.remoteIP, dash(e.user), e.time.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(e.method+" "+e.path+" "+e.proto), e.status, bytes,
+		strconv.Quote(dash(e.referer)), strconv.Quote(dash(e.userAgent)))
+}
+
+func (e accessLogEntry) durationMS() float64 {
+	return float64(e.duration.Microseconds()) / 1000
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// logfmtValue returns v, quoted if it is empty or contains spaces, quotes or
+// equals signs.
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \"=\t\n") {
+		return strconv.Quote(v)
+	}
+	return v
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestAccessLogFormats(t *testing.T) {
+	for name, format := range map[string]Format{
+		"combined": FormatCombined,
+		"json":     FormatJSON,
+		"logfmt":   FormatLogfmt,
+	} {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewFake(time.Date(2026, time.October, 10, 13, 55, 36, 0, time.UTC))
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				clk.Advance(1500 * time.Microsecond)
+				if r.URL.Path == "/missing" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte("hello"))
+			})
+
+			var buf bytes.Buffer
+			s := New("", h, WithClock(clk), WithAccessLog(&buf), WithAccessLogFormat(format))
+
+			r := httptest.NewRequest("GET", "/widgets?page=2", nil)
+			r.SetBasicAuth("alice", "secret")
+			r.Header.Set("Referer", "https://example.com/")
+			r.Header.Set("User-Agent", "curl/8.0")
+			r.Header.Set(RequestIDHeader, "req-1")
+			serve(s.server.Handler, r)
+			serve(s.server.Handler, httptest.NewRequest("POST", "/missing", nil))
+
+			golden := filepath.Join("testdata", "accesslog", name+".golden")
+			if *update {
+				os.MkdirAll(filepath.Dir(golden), 0o755)
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("access log does not match %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestAccessLogGeneratedRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	s := New("", okHandler, WithAccessLog(&buf), WithAccessLogFormat(FormatLogfmt), WithRequestID())
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	id := w.Header().Get(RequestIDHeader)
+	if id == "" {
+		t.Fatal("expected a generated request ID")
+	}
+	if !strings.Contains(buf.String(), id) {
+		t.Errorf("expected the access log to include request ID %s, got %q", id, buf.String())
+	}
+}
+
+func TestAccessLogRequestLogger(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /widgets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	// Whichever order the options are given in, the access log uses the
+	// logger derived for the request.
+	for _, first := range []bool{true, false} {
+		base := newTestLogger()
+		clk := clock.NewFake(time.Now())
+		opts := []Option{WithClock(clk), WithRequestID(), WithRequestLogger(base)}
+		if first {
+			opts = append([]Option{WithAccessLog(nil)}, opts...)
+		} else {
+			opts = append(opts, WithAccessLog(nil))
+		}
+		s := New("", mux, opts...)
+
+		r := httptest.NewRequest("GET", "/widgets/1", nil)
+		r.Header.Set(RequestIDHeader, "abc123")
+		r.RemoteAddr = "192.0.2.1:1234"
+		serve(s.server.Handler, r)
+
+		entries := base.Entries()
+		want := "INFO request [method GET path /widgets/1 request_id abc123 status 418 bytes 0 duration_ms 0 remote_ip 192.0.2.1 route GET /widgets/{id}]"
+		if len(entries) != 1 || entries[0] != want {
+			t.Errorf("access log first %t: expected entries [%s], got %v", first, want, entries)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying principal, the identity of the
+// caller, such as a user or API key record. It is intended for use by the
+// verify function passed to Authenticate.
+func WithPrincipal(ctx context.Context, principal any) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx with WithPrincipal,
+// and whether there was one.
+func PrincipalFromContext(ctx context.Context) (any, bool) {
+	p := ctx.Value(principalKey{})
+	return p, p != nil
+}
+
+// Authenticate returns a middleware that calls verify for every request to
+// check its credentials, such as a bearer token or API key in a header or
+// cookie. verify returns the context to serve the request with, usually
+// enriched with the authenticated principal using WithPrincipal, which the
+// handler can then read with PrincipalFromContext.
+//
+// If verify returns an error, the request is rejected with 401 Unauthorized,
+// and the cause given to the error responder wraps both ErrUnauthorized and
+// the error from verify. The handler is not called.
+func Authenticate(verify func(ctx context.Context, r *http.Request) (context.Context, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := verify(r.Context(), r)
+			if err != nil {
+				writeError(w, r, http.StatusUnauthorized, fmt.Errorf("%w: %w", ErrUnauthorized, err))
+				return
+			}
+			if ctx != nil {
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithAuthenticator modifies the server to authenticate every request with
+// verify. See Authenticate.
+func WithAuthenticator(verify func(ctx context.Context, r *http.Request) (context.Context, error)) Option {
+	return func(s *Server) *Server {
+		s.use("authenticator", Authenticate(verify))
+		return s
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errBadToken = errors.New("bad token")
+
+func verifyToken(ctx context.Context, r *http.Request) (context.Context, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token != "secret" {
+		return nil, errBadToken
+	}
+	return WithPrincipal(ctx, "alice"), nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	var causes []error
+	s := New(":8080", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFromContext(r.Context())
+		fmt.Fprintf(w, "%v %t", p, ok)
+	}),
+		WithAuthenticator(verifyToken),
+		WithErrorResponder(func(w http.ResponseWriter, r *http.Request, status int, cause error) {
+			causes = append(causes, cause)
+			jsonErrors(w, r, status, cause)
+		}),
+	)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := serve(s.server.Handler, r)
+	if w.Code != http.StatusOK || w.Body.String() != "alice true" {
+		t.Errorf("expected the principal to reach the handler, got %d %q", w.Code, w.Body.String())
+	}
+
+	for _, auth := range []string{"", "Bearer wrong"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", auth)
+		w := serve(s.server.Handler, r)
+		var env errorEnvelope
+		json.NewDecoder(w.Body).Decode(&env)
+		if w.Code != http.StatusUnauthorized || env.Status != http.StatusUnauthorized {
+			t.Errorf("%q: expected 401, got %d %+v", auth, w.Code, env)
+		}
+	}
+	for _, cause := range causes {
+		if !errors.Is(cause, ErrUnauthorized) || !errors.Is(cause, errBadToken) {
+			t.Errorf("expected the cause to wrap ErrUnauthorized and the verify error, got %v", cause)
+		}
+	}
+}
+
+func TestPrincipalFromContextMissing(t *testing.T) {
+	if p, ok := PrincipalFromContext(context.Background()); ok {
+		t.Errorf("expected no principal, got %v", p)
+	}
+}
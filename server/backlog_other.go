@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package server
+
+import "net"
+
+// setBacklog does nothing, since changing the backlog of a listening socket
+// is not supported on this platform.
+func setBacklog(ln net.Listener, n int) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package server
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queued returns how many of tries concurrent connections to addr complete
+// the TCP handshake while nothing accepts them.
+func queued(addr string, tries int) int {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		n  int
+	)
+	for i := 0; i < tries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.DialTimeout("tcp", addr, 500*time.Millisecond)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			n++
+			mu.Unlock()
+			time.AfterFunc(time.Second, func() { conn.Close() })
+		}()
+	}
+	wg.Wait()
+	return n
+}
+
+func TestListenBacklog(t *testing.T) {
+	s := New("127.0.0.1:0", okHandler, WithListenBacklog(2))
+	ln, err := s.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Linux queues one more connection than the backlog.
+	if n := queued(ln.Addr().String(), 10); n > 3 {
+		t.Errorf("expected at most 3 queued connections with a backlog of 2, got %d", n)
+	}
+
+	def := New("127.0.0.1:0", okHandler)
+	ln, err = def.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if n := queued(ln.Addr().String(), 10); n != 10 {
+		t.Errorf("expected all 10 connections to be queued by default, got %d", n)
+	}
+}
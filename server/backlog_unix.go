@@ -0,0 +1,29 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package server
+
+import (
+	"net"
+	"syscall"
+)
+
+// setBacklog sets the accept queue length of a listening TCP socket by calling
+// listen again, which updates the backlog of a socket that is already
+// listening.
+func setBacklog(ln net.Listener, n int) error {
+	tl, ok := ln.(*net.TCPListener)
+	if !ok {
+		return nil
+	}
+	rc, err := tl.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var lerr error
+	if err := rc.Control(func(fd uintptr) {
+		lerr = syscall.Listen(int(fd), n)
+	}); err != nil {
+		return err
+	}
+	return lerr
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// LargeUploadBytes is the Content-Length from which WithUploadReadTimeout
+// treats a request as a large upload. Chunked requests, whose length is not
+// known in advance, are always treated as large uploads.
+const LargeUploadBytes = 1 << 20
+
+// WithMaxBodyBytes modifies the server to limit request bodies to n bytes.
+// Requests whose Content-Length exceeds n are rejected with 413 Request Entity
+// Too Large and ErrBodyTooLarge before the handler runs.
+//
+// Chunked requests have no Content-Length, so they cannot be rejected up
+// front. Instead, the body is wrapped with http.MaxBytesReader, and a read
+// that would take it past n returns an *http.MaxBytesError. Handlers should
+// check for that error, as DecodeJSON does, and respond with 413 themselves.
+// The connection is closed after such a response, since the rest of the body
+// is never read.
+func WithMaxBodyBytes(n int64) Option {
+	return func(s *Server) *Server {
+		s.use("max_body_bytes", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.ContentLength > n {
+					w.Header().Set("Connection", "close")
+					writeError(w, r, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+					return
+				}
+				if r.Body != nil && r.Body != http.NoBody {
+					r.Body = http.MaxBytesReader(w, r.Body, n)
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
+
+// WithUploadReadTimeout modifies the server to allow large uploads d from the
+// start of the handler to finish reading the request, instead of the read
+// timeout set with WithReadTimeout, which covers the header and body together
+// and is usually too short for them. A request is a large upload if it is
+// chunked or its Content-Length is at least LargeUploadBytes; all other
+// requests keep the normal read timeout.
+//
+// The write timeout set with WithWriteTimeout is counted from the same point
+// as the read timeout, so for large uploads it is pushed back too, to d plus
+// the write timeout, leaving the handler the usual time to respond once the
+// upload has been read.
+//
+// Because the extension is granted before the body is read, it should be
+// combined with WithMaxBodyBytes, so that a client can hold a connection open
+// for at most d and send at most the configured number of bytes in that time.
+// If the underlying ResponseWriter does not support deadlines, the normal read
+// timeout applies.
+func WithUploadReadTimeout(d time.Duration) Option {
+	return func(s *Server) *Server {
+		s.use("upload_read_timeout", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.ContentLength < 0 || r.ContentLength >= LargeUploadBytes {
+					rc := http.NewResponseController(w)
+					now := time.Now()
+					rc.SetReadDeadline(now.Add(d))
+					if wt := s.server.WriteTimeout; wt > 0 {
+						rc.SetWriteDeadline(now.Add(d + wt))
+					}
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll is a handler that reads the whole request body, responding with 413
+// if it is too large.
+var readAll = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if _, err := io.ReadAll(r.Body); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+})
+
+// onlyReader hides everything but Read, so the client can't learn the length
+// of the body and sends it chunked.
+type onlyReader struct{ io.Reader }
+
+func TestMaxBodyBytesChunked(t *testing.T) {
+	const limit = 1024
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Transfer-Encoding", strings.Join(r.TransferEncoding, ","))
+		readAll(w, r)
+	})
+	s := New(":8080", h, WithMaxBodyBytes(limit))
+	ts := newTestServer(t, s)
+
+	for _, tt := range []struct {
+		name string
+		size int
+		want int
+	}{
+		{"under", limit - 1, http.StatusNoContent},
+		{"at", limit, http.StatusNoContent},
+		{"over", limit + 1, http.StatusRequestEntityTooLarge},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body := onlyReader{strings.NewReader(strings.Repeat("a", tt.size))}
+			req, _ := http.NewRequest("POST", ts.URL, body)
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("X-Transfer-Encoding"); got != "chunked" {
+				t.Fatalf("expected a chunked request, got %q", got)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestMaxBodyBytesContentLength(t *testing.T) {
+	s := New(":8080", readAll, WithMaxBodyBytes(1024), WithErrorResponder(jsonErrors))
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat("a", 1025)))
+	w := serve(s.server.Handler, r)
+	var env errorEnvelope
+	json.NewDecoder(w.Body).Decode(&env)
+	if w.Code != http.StatusRequestEntityTooLarge || env.Error != ErrBodyTooLarge.Error() {
+		t.Errorf("expected 413 with ErrBodyTooLarge, got %d %q", w.Code, env.Error)
+	}
+	if got := w.Header().Get("Connection"); got != "close" {
+		t.Errorf("expected Connection: close, got %q", got)
+	}
+}
+
+func TestUploadReadTimeout(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"without", nil, false},
+		{"with", []Option{WithUploadReadTimeout(5 * time.Second)}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithReadTimeout(200 * time.Millisecond),
+				WithWriteTimeout(300 * time.Millisecond),
+			}, tt.opts...)
+			s := New(":8080", readAll, opts...)
+			ts := httptest.NewUnstartedServer(s.server.Handler)
+			ts.Config.ReadTimeout = s.server.ReadTimeout
+			ts.Config.WriteTimeout = s.server.WriteTimeout
+			ts.Start()
+			defer ts.Close()
+
+			// Send a chunked body that takes longer than both the read and write
+			// timeouts.
+			pr, pw := io.Pipe()
+			go func() {
+				for i := 0; i < 5; i++ {
+					pw.Write([]byte("chunk"))
+					time.Sleep(100 * time.Millisecond)
+				}
+				pw.Close()
+			}()
+			req, _ := http.NewRequest("POST", ts.URL, pr)
+			resp, err := ts.Client().Do(req)
+			ok := err == nil && resp.StatusCode == http.StatusNoContent
+			if err == nil {
+				resp.Body.Close()
+			}
+			if ok != tt.want {
+				t.Errorf("expected success %t, got %t (err %v)", tt.want, ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"net/http"
+)
+
+type responseBufferKey struct{}
+
+// ResponseBuffering returns a middleware that holds back each response until
+// the handler returns, so a handler that fails partway through writing can
+// still replace what it wrote with an error by calling RespondError. Once a
+// response grows past maxBytes, or the handler flushes it, what has been
+// buffered is sent and the rest of the response streams as usual.
+//
+// Middleware that transforms the response body, such as WithCompression,
+// should be installed before WithResponseBuffering, so that it sees only the
+// response that is finally sent.
+func ResponseBuffering(maxBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bw := &bufferWriter{ResponseWriter: w, max: maxBytes, header: w.Header().Clone()}
+			ctx := context.WithValue(r.Context(), responseBufferKey{}, bw)
+			next.ServeHTTP(bw, r.WithContext(ctx))
+			bw.finish()
+		})
+	}
+}
+
+// WithResponseBuffering modifies the server to buffer responses of up to
+// maxBytes so handlers can decide the status at the end. See
+// ResponseBuffering.
+func WithResponseBuffering(maxBytes int) Option {
+	return func(s *Server) *Server {
+		s.use("response_buffering", ResponseBuffering(maxBytes))
+		return s
+	}
+}
+
+// RespondError discards the response written so far for r, including any
+// headers set since ResponseBuffering started buffering it, and writes an
+// error response with status using the server's error responder. It reports
+// whether it did so; it returns false, and writes nothing, if part of the
+// response has already been sent or r is not being buffered, in which case
+// the handler can only stop writing and return.
+func RespondError(w http.ResponseWriter, r *http.Request, status int, cause error) bool {
+	bw, ok := r.Context().Value(responseBufferKey{}).(*bufferWriter)
+	if !ok || !bw.reset() {
+		return false
+	}
+	writeError(w, r, status, cause)
+	return true
+}
+
+// bufferWriter holds a response in memory until it is finished, or until it
+// outgrows max and starts streaming.
+type bufferWriter struct {
+	http.ResponseWriter
+	max int
+
+	// header is the response header as it was before the handler ran, which
+	// reset restores.
+	header    http.Header
+	status    int
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	if w.streaming || code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.buf.Len()+len(p) > w.max {
+		if err := w.stream(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush sends whatever has been buffered and switches to streaming, then
+// flushes the underlying writer, if it supports flushing.
+func (w *bufferWriter) Flush() {
+	if !w.streaming {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.stream()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *bufferWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// stream sends the status and buffered body and passes later writes through.
+func (w *bufferWriter) stream() error {
+	w.streaming = true
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf = bytes.Buffer{}
+	return err
+}
+
+// finish sends the buffered response, if the handler wrote one.
+func (w *bufferWriter) finish() {
+	if w.streaming || w.status == 0 {
+		return
+	}
+	w.stream()
+}
+
+// reset discards the buffered response, reporting false if it is too late
+// because the response is already streaming.
+func (w *bufferWriter) reset() bool {
+	if w.streaming {
+		return false
+	}
+	hdr := w.ResponseWriter.Header()
+	clear(hdr)
+	maps.Copy(hdr, w.header)
+	w.status = 0
+	w.buf.Reset()
+	return true
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errRender = errors.New("render failed")
+
+func TestResponseBufferingRewriteOnError(t *testing.T) {
+	var responded bool
+	h := ResponseBuffering(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		io.WriteString(w, "id,name\n1,alice\n")
+		responded = RespondError(w, r, http.StatusInternalServerError, errRender)
+	}))
+
+	w := serve(h, httptest.NewRequest("GET", "/", nil))
+	if !responded {
+		t.Fatal("RespondError reported the response was already sent")
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "alice") {
+		t.Errorf("partial response was sent: %q", w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got == "text/csv" {
+		t.Error("header set by the handler was not discarded")
+	}
+}
+
+func TestResponseBufferingErrorResponder(t *testing.T) {
+	s := New("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "partial")
+		RespondError(w, r, http.StatusBadGateway, errRender)
+	}), WithErrorResponder(jsonErrors), WithResponseBuffering(1024))
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected the error responder's response, got Content-Type %q", got)
+	}
+}
+
+func TestResponseBufferingSuccess(t *testing.T) {
+	h := ResponseBuffering(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "created")
+	}))
+
+	w := serve(h, httptest.NewRequest("POST", "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "created" {
+		t.Errorf("got %d %q, want 201 \"created\"", w.Code, w.Body)
+	}
+}
+
+func TestResponseBufferingFallbackToStreaming(t *testing.T) {
+	const limit = 16
+	var (
+		flushedEarly bool
+		responded    bool
+	)
+	var rec *httptest.ResponseRecorder
+	h := ResponseBuffering(limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("a", limit))
+		if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Flushed {
+			t.Error("response at the limit was not buffered")
+		}
+		io.WriteString(w, "b")
+		flushedEarly = rec.Body.Len() == limit+1
+		responded = RespondError(w, r, http.StatusInternalServerError, errRender)
+	}))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !flushedEarly {
+		t.Error("response over the limit was not streamed")
+	}
+	if responded {
+		t.Error("RespondError rewrote a response that had already been sent")
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got := rec.Body.String(); got != strings.Repeat("a", limit)+"b" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
+
+func TestResponseBufferingFlush(t *testing.T) {
+	h := ResponseBuffering(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "event")
+		http.NewResponseController(w).Flush()
+		if RespondError(w, r, http.StatusInternalServerError, errRender) {
+			t.Error("RespondError rewrote a flushed response")
+		}
+	}))
+
+	w := serve(h, httptest.NewRequest("GET", "/", nil))
+	if !w.Flushed || w.Body.String() != "event" {
+		t.Errorf("got flushed=%v body %q", w.Flushed, w.Body)
+	}
+}
+
+func TestRespondErrorWithoutBuffering(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if RespondError(w, r, http.StatusInternalServerError, errRender) {
+			t.Error("RespondError responded without ResponseBuffering")
+		}
+	})
+	serve(h, httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ErrChildKilled is returned, wrapped, by Wait when a child process passed to
+// ForwardSignalTo had to be killed because it did not exit within the
+// shutdown timeout.
+var ErrChildKilled = errors.New("server: child process killed after shutdown timeout")
+
+// ForwardSignalTo modifies the server to manage the lifecycle of cmd, a
+// subprocess such as a sidecar, along with its own. When the server begins
+// shutting down, at the same time as workers started with Run are stopped,
+// cmd is sent SIGTERM, and Serve waits for it to exit before returning. The
+// wait shares the shutdown timeout with the rest of the shutdown, and if cmd
+// is still running once the timeout has passed, it is killed and Wait reports
+// ErrChildKilled.
+//
+// If Serve returns because of a serve error instead, cmd is still sent
+// SIGTERM, but only Wait waits for it to exit.
+//
+// cmd must have been started by the time the server shuts down, and the
+// server takes over waiting for it, so the caller must not call cmd.Wait. If
+// cmd was never started, or Serve is never called, nothing is done.
+func ForwardSignalTo(cmd *exec.Cmd) Option {
+	return func(s *Server) *Server {
+		s.children = append(s.children, cmd)
+		return s
+	}
+}
+
+// stopChildren sends SIGTERM to the processes passed to ForwardSignalTo and
+// waits for them to exit as workers, killing any that are still running at
+// deadline.
+func (s *Server) stopChildren(deadline time.Time) {
+	for _, cmd := range s.children {
+		s.Run(func(context.Context) error {
+			return s.stopChild(cmd, deadline)
+		})
+	}
+}
+
+func (s *Server) stopChild(cmd *exec.Cmd, deadline time.Time) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		cmd.Process.Kill()
+	}
+
+	timer := s.clock.NewTimer(deadline.Sub(s.clock.Now()))
+	defer timer.Stop()
+	select {
+	case err := <-exited:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The child was asked to stop, so a non-zero exit or death by
+			// SIGTERM is expected.
+			return nil
+		}
+		return err
+	case <-timer.C():
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("%w: pid %d", ErrChildKilled, cmd.Process.Pid)
+	}
+}
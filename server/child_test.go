@@ -0,0 +1,110 @@
+//go:build unix
+
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+// TestHelperChild is not a real test. It is run as a child process by the
+// ForwardSignalTo tests, and behaves as described by SERVER_TEST_CHILD.
+func TestHelperChild(t *testing.T) {
+	mode := os.Getenv("SERVER_TEST_CHILD")
+	if mode == "" {
+		return
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	fmt.Println("ready")
+	<-sigs
+	if mode == "ignore" {
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+// startChild starts the test binary as a child process running
+// TestHelperChild in the given mode, and waits for it to be ready.
+func startChild(t *testing.T, mode string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperChild$")
+	cmd.Env = append(os.Environ(), "SERVER_TEST_CHILD="+mode)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if line, _ := bufio.NewReader(out).ReadString('\n'); line != "ready\n" {
+		cmd.Process.Kill()
+		t.Fatalf("expected the child to be ready, got %q", line)
+	}
+	return cmd
+}
+
+func TestForwardSignalTo(t *testing.T) {
+	cmd := startChild(t, "exit")
+	s := New("", okHandler, WithOutputWriter(io.Discard), ForwardSignalTo(cmd))
+
+	_, errc := start(t, s)
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+	if cmd.ProcessState == nil || !cmd.ProcessState.Success() {
+		t.Errorf("expected the child to have exited cleanly before Serve returned, got %v", cmd.ProcessState)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("expected no errors, got %v", err)
+	}
+}
+
+func TestForwardSignalToKillsAfterTimeout(t *testing.T) {
+	cmd := startChild(t, "ignore")
+	clk := clock.NewFake(time.Now())
+	s := New("", okHandler,
+		WithOutputWriter(io.Discard),
+		WithErrorWriter(io.Discard),
+		WithClock(clk),
+		WithShutdown(time.Minute),
+		ForwardSignalTo(cmd),
+	)
+
+	_, errc := start(t, s)
+	s.signals <- syscall.SIGTERM
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(time.Minute)
+	if err := <-errc; err != nil {
+		t.Errorf("expected Serve to return once the child was killed, got %v", err)
+	}
+	if err := s.Wait(); !errors.Is(err, ErrChildKilled) {
+		t.Errorf("expected error %v, got %v", ErrChildKilled, err)
+	}
+	if cmd.ProcessState == nil || cmd.ProcessState.Success() {
+		t.Errorf("expected the child to have been killed, got %v", cmd.ProcessState)
+	}
+}
+
+func TestForwardSignalToNotStarted(t *testing.T) {
+	s := New("", okHandler, WithOutputWriter(io.Discard), ForwardSignalTo(exec.Command("true")))
+
+	_, errc := start(t, s)
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// CleanPath is a middleware that rejects request paths that look like an
+// attack, and normalizes the rest before they reach the router.
+//
+// Paths that try to climb out of the root with a ".." segment, in plain or
+// percent-encoded form, and paths containing an encoded separator ("%2F" or
+// "%5C"), a double-encoded separator or dot (such as "%252F"), a backslash or
+// a NUL byte, get 400 Bad Request with the cause ErrInvalidPath. Benign
+// irregularities, such as repeated slashes and "." segments, are cleaned up,
+// keeping any trailing slash, and the request is served with the canonical
+// path.
+//
+// Use CleanPathRedirect to redirect GET and HEAD requests to the canonical
+// path instead, so clients and caches learn it.
+func CleanPath(next http.Handler) http.Handler {
+	return cleanPath(next, false)
+}
+
+// CleanPathRedirect is like CleanPath, but answers GET and HEAD requests for a
+// path that needs cleaning with a 308 Permanent Redirect to the canonical path.
+// Requests with other methods are served with the cleaned path, as with
+// CleanPath.
+func CleanPathRedirect(next http.Handler) http.Handler {
+	return cleanPath(next, true)
+}
+
+// WithCleanPath modifies the server to validate and normalize request paths.
+// If redirect is set, it uses CleanPathRedirect, otherwise CleanPath. It
+// should be added before any middleware that looks at the path.
+func WithCleanPath(redirect bool) Option {
+	return func(s *Server) *Server {
+		s.use("clean_path", func(next http.Handler) http.Handler {
+			return cleanPath(next, redirect)
+		})
+		return s
+	}
+}
+
+func cleanPath(next http.Handler, redirect bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validPath(r.URL.EscapedPath(), r.URL.Path) {
+			writeError(w, r, http.StatusBadRequest, ErrInvalidPath)
+			return
+		}
+
+		clean := canonicalPath(r.URL.Path)
+		if clean == r.URL.Path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if redirect && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			u := *r.URL
+			u.Path, u.RawPath = clean, ""
+			http.Redirect(w, r, u.RequestURI(), http.StatusPermanentRedirect)
+			return
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path, r2.URL.RawPath = clean, ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// validPath reports whether a path, in its escaped and decoded forms, is free
+// of traversal and encoding tricks.
+func validPath(escaped, decoded string) bool {
+	lower := strings.ToLower(escaped)
+	for _, bad := range []string{"%2f", "%5c", "%00", "%252e", "%252f", "%255c", "%2500"} {
+		if strings.Contains(lower, bad) {
+			return false
+		}
+	}
+	if strings.ContainsAny(decoded, "\\\x00") {
+		return false
+	}
+	for _, seg := range strings.Split(decoded, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalPath returns p with repeated slashes and "." segments removed,
+// keeping a trailing slash.
+func canonicalPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	clean := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return clean
+}
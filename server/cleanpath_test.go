@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	var got string
+	h := CleanPath(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	}))
+
+	for _, tt := range []struct {
+		name, target string
+		status       int
+		path         string
+	}{
+		{"clean", "/a/b", http.StatusOK, "/a/b"},
+		{"trailing slash", "/a/b/", http.StatusOK, "/a/b/"},
+		{"double slash", "//a//b", http.StatusOK, "/a/b"},
+		{"dot segment", "/a/./b/", http.StatusOK, "/a/b/"},
+		{"encoded percent", "/100%25", http.StatusOK, "/100%"},
+		{"traversal", "/a/../../etc/passwd", http.StatusBadRequest, ""},
+		{"encoded traversal", "/a/%2e%2e/b", http.StatusBadRequest, ""},
+		{"encoded slash", "/a%2Fb", http.StatusBadRequest, ""},
+		{"encoded backslash", "/a%5cb", http.StatusBadRequest, ""},
+		{"double encoded slash", "/a%252Fb", http.StatusBadRequest, ""},
+		{"double encoded dot", "/%252e%252e/etc", http.StatusBadRequest, ""},
+		{"backslash", `/a\..\b`, http.StatusBadRequest, ""},
+		{"nul", "/a%00b", http.StatusBadRequest, ""},
+	} {
+		got = ""
+		w := serve(h, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+		if got != tt.path {
+			t.Errorf("%s: expected path %q, got %q", tt.name, tt.path, got)
+		}
+	}
+}
+
+func TestCleanPathRedirect(t *testing.T) {
+	h := CleanPathRedirect(okHandler)
+
+	w := serve(h, httptest.NewRequest("GET", "//a/./b?x=1", nil))
+	if w.Code != http.StatusPermanentRedirect {
+		t.Fatalf("expected status %d, got %d", http.StatusPermanentRedirect, w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/a/b?x=1" {
+		t.Errorf("expected redirect to %q, got %q", "/a/b?x=1", loc)
+	}
+
+	// Other methods are served with the cleaned path rather than redirected.
+	if w := serve(h, httptest.NewRequest("POST", "//a/b", nil)); w.Code != http.StatusOK {
+		t.Errorf("expected POST to be served, got %d", w.Code)
+	}
+	if w := serve(h, httptest.NewRequest("GET", "/a/b", nil)); w.Code != http.StatusOK {
+		t.Errorf("expected a clean path to be served, got %d", w.Code)
+	}
+}
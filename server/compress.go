@@ -0,0 +1,213 @@
+package server
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MinCompressBytes is the smallest response, by Content-Length, that
+// WithCompression compresses. Responses without a Content-Length are always
+// compressed.
+const MinCompressBytes = 1024
+
+// compressionEncodings lists the content codings WithCompression supports, in
+// order of preference when the client accepts several equally.
+var compressionEncodings = []string{"gzip", "deflate"}
+
+// WithCompression modifies the server to compress responses with gzip or
+// deflate, whichever the client's Accept-Encoding header prefers. Responses
+// that already have a Content-Encoding, that have no body, that are partial
+// (206 or with a Content-Range), or whose Content-Length is below
+// MinCompressBytes are sent as is.
+//
+// A client that forbids the identity coding, with "identity;q=0" or "*;q=0",
+// and accepts neither gzip nor deflate gets 406 Not Acceptable.
+func WithCompression() Option {
+	return func(s *Server) *Server {
+		pools := map[string]*sync.Pool{
+			"gzip": {New: func() any {
+				w, _ := gzip.NewWriterLevel(nil, s.compressionLevel)
+				return w
+			}},
+			"deflate": {New: func() any {
+				w, _ := flate.NewWriter(nil, s.compressionLevel)
+				return w
+			}},
+		}
+
+		s.use("compression", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Vary", "Accept-Encoding")
+				enc, ok := negotiateEncoding(r.Header.Values("Accept-Encoding"))
+				if !ok {
+					writeError(w, r, http.StatusNotAcceptable, ErrEncodingNotAllowed)
+					return
+				}
+				if enc == "identity" || r.Method == http.MethodHead {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				cw := &compressWriter{ResponseWriter: w, encoding: enc, pool: pools[enc]}
+				defer cw.close()
+				next.ServeHTTP(cw, r)
+			})
+		})
+		return s
+	}
+}
+
+// WithCompressionLevel modifies the level used by WithCompression, from
+// gzip.BestSpeed (1) to gzip.BestCompression (9), trading CPU time for smaller
+// responses. Levels outside that range, other than gzip.HuffmanOnly (-2),
+// select gzip.DefaultCompression.
+func WithCompressionLevel(level int) Option {
+	return func(s *Server) *Server {
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			level = gzip.DefaultCompression
+		}
+		s.compressionLevel = level
+		return s
+	}
+}
+
+// negotiateEncoding returns the content coding to use for a response given
+// the request's Accept-Encoding header values, following RFC 9110 section
+// 12.5.3. It returns false if no supported coding, including identity, is
+// acceptable.
+func negotiateEncoding(values []string) (string, bool) {
+	if len(values) == 0 {
+		return "identity", true
+	}
+
+	explicit := make(map[string]float64)
+	star, hasStar := 0.0, false
+	for _, qv := range parseQList(strings.Join(values, ",")) {
+		if _, seen := explicit[qv.value]; seen {
+			continue
+		}
+		if qv.value == "*" {
+			if !hasStar {
+				star, hasStar = qv.q, true
+			}
+			continue
+		}
+		explicit[qv.value] = qv.q
+	}
+
+	quality := func(enc string) float64 {
+		if q, ok := explicit[enc]; ok {
+			return q
+		}
+		if hasStar {
+			return star
+		}
+		if enc == "identity" {
+			return 1
+		}
+		return 0
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range append(compressionEncodings, "identity") {
+		if q := quality(enc); q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best, best != ""
+}
+
+// compressWriter compresses the body written through it, deciding whether to
+// compress once the header is written.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	pool     *sync.Pool
+
+	wroteHeader bool
+	zw          compressor
+}
+
+// compressor is implemented by both *gzip.Writer and *flate.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader || code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+
+	hdr := w.Header()
+	if w.shouldCompress(code) {
+		w.zw = w.pool.Get().(compressor)
+		w.zw.Reset(w.ResponseWriter)
+		hdr.Set("Content-Encoding", w.encoding)
+		hdr.Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) shouldCompress(code int) bool {
+	hdr := w.Header()
+	if code == http.StatusNoContent || code == http.StatusNotModified || hdr.Get("Content-Encoding") != "" {
+		return false
+	}
+	// Content-Range describes the uncompressed bytes, so a partial response
+	// must be sent as is.
+	if code == http.StatusPartialContent || hdr.Get("Content-Range") != "" {
+		return false
+	}
+	if cl := hdr.Get("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		return err == nil && n >= MinCompressBytes
+	}
+	return true
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.zw == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.zw.Write(p)
+}
+
+// Flush flushes any buffered compressed data, then the underlying writer.
+func (w *compressWriter) Flush() {
+	if w.zw != nil {
+		w.zw.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the compressed stream, if one was started, and returns the
+// compressor to its pool.
+func (w *compressWriter) close() {
+	if w.zw == nil {
+		return
+	}
+	w.zw.Close()
+	w.zw.Reset(io.Discard)
+	w.pool.Put(w.zw)
+	w.zw = nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                             "identity",
+		"gzip":                         "gzip",
+		"deflate, gzip":                "gzip",
+		"gzip;q=0.5, deflate":          "deflate",
+		"br, zstd":                     "identity",
+		"*":                            "gzip",
+		"*;q=0.5, deflate;q=0.8":       "deflate",
+		"gzip;q=0, *":                  "deflate",
+		"identity;q=0, gzip;q=0.1":     "gzip",
+		"gzip;q=0, deflate;q=0":        "identity",
+		"identity;q=0.9, gzip;q=0.2":   "identity",
+		"GZIP;Q=1":                     "gzip",
+		"identity;q=0, *;q=0, deflate": "deflate",
+	} {
+		var values []string
+		if header != "" {
+			values = []string{header}
+		}
+		got, ok := negotiateEncoding(values)
+		if !ok || got != want {
+			t.Errorf("%q: expected %q, got %q (ok=%v)", header, want, got, ok)
+		}
+	}
+
+	for _, header := range []string{"identity;q=0", "*;q=0", "br, identity;q=0"} {
+		if got, ok := negotiateEncoding([]string{header}); ok {
+			t.Errorf("%q: expected nothing acceptable, got %q", header, got)
+		}
+	}
+}
+
+var largeBody = strings.Repeat("compress me ", 200)
+
+func TestCompression(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, largeBody)
+	})
+	s := New("", h, WithCompression(), WithCompressionLevel(gzip.BestSpeed))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "deflate;q=0.5, gzip")
+	w := serve(s.server.Handler, r)
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("expected Vary Accept-Encoding, got %q", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != largeBody {
+		t.Errorf("expected the decompressed body to match")
+	}
+
+	w = serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no encoding without Accept-Encoding, got %q", got)
+	}
+}
+
+func TestCompressionIdentityForbidden(t *testing.T) {
+	s := New("", okHandler, WithCompression())
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "br, identity;q=0")
+	if w := serve(s.server.Handler, r); w.Code != http.StatusNotAcceptable {
+		t.Errorf("expected status %d, got %d", http.StatusNotAcceptable, w.Code)
+	}
+}
+
+func TestCompressionSmallResponse(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2")
+		io.WriteString(w, "ok")
+	})
+	s := New("", h, WithCompression())
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := serve(s.server.Handler, r)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected a small response not to be compressed, got %q", got)
+	}
+	if got := w.Body.String(); got != "ok" {
+		t.Errorf("expected body %q, got %q", "ok", got)
+	}
+}
+
+func TestCompressionRange(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "body.txt", time.Time{}, strings.NewReader(largeBody))
+	})
+	s := New("", h, WithCompression())
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Range", "bytes=0-1999")
+	w := serve(s.server.Handler, r)
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d", http.StatusPartialContent, w.Code)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected a partial response not to be compressed, got %q", got)
+	}
+	if got := w.Body.String(); got != largeBody[:2000] {
+		t.Errorf("expected the first 2000 bytes, got %d bytes", len(got))
+	}
+}
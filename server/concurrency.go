@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithMaxConcurrentRequests modifies the server to handle at most n requests
+// at once. This differs from limiting connections, since an HTTP/2 connection
+// can carry many requests at the same time.
+//
+// By default, a request that arrives while n others are being handled is
+// rejected immediately with 503 Service Unavailable and a "Retry-After: 1"
+// header, rather than queued, so a flood of requests cannot consume unbounded
+// memory. Use WithConcurrencyQueueTimeout to have requests wait for a slot
+// instead. An n that is not positive leaves requests unlimited.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(s *Server) *Server {
+		if n <= 0 {
+			return s
+		}
+		sem := make(chan struct{}, n)
+		s.use("max_concurrent_requests", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !s.acquire(sem, r) {
+					if r.Context().Err() != nil {
+						return
+					}
+					w.Header().Set("Retry-After", "1")
+					writeError(w, r, http.StatusServiceUnavailable, ErrTooManyRequests)
+					return
+				}
+				defer func() { <-sem }()
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
+
+// WithConcurrencyQueueTimeout modifies the limit set by
+// WithMaxConcurrentRequests so that a request arriving at the limit waits up to
+// d for another request to finish before it is rejected. A request whose
+// context is cancelled while waiting is abandoned without a response.
+func WithConcurrencyQueueTimeout(d time.Duration) Option {
+	return func(s *Server) *Server {
+		s.concurrencyQueue = d
+		return s
+	}
+}
+
+// acquire takes a slot in sem for r, waiting up to the configured queue
+// timeout. It reports whether a slot was taken.
+func (s *Server) acquire(sem chan struct{}, r *http.Request) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+	}
+	if s.concurrencyQueue <= 0 {
+		return false
+	}
+
+	t := s.clock.NewTimer(s.concurrencyQueue)
+	defer t.Stop()
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-t.C():
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
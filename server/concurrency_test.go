@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+// blocking returns a handler that signals on entered and then waits for
+// release to be closed.
+func blocking() (h http.Handler, entered chan struct{}, release chan struct{}) {
+	entered, release = make(chan struct{}, 10), make(chan struct{})
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	})
+	return h, entered, release
+}
+
+func TestMaxConcurrentRequestsReject(t *testing.T) {
+	h, entered, release := blocking()
+	s := New("", h, WithMaxConcurrentRequests(2))
+
+	done := make(chan struct{})
+	for range 2 {
+		go func() {
+			serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+			done <- struct{}{}
+		}()
+		<-entered
+	}
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d over the limit, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+
+	close(release)
+	<-done
+	<-done
+	w = serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d once under the limit, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestMaxConcurrentRequestsQueue(t *testing.T) {
+	h, entered, release := blocking()
+	clk := clock.NewFake(time.Now())
+	s := New("", h, WithClock(clk), WithMaxConcurrentRequests(1), WithConcurrencyQueueTimeout(time.Second))
+
+	go serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	<-entered
+
+	// A queued request times out if no slot frees up.
+	codes := make(chan int)
+	go func() { codes <- serve(s.server.Handler, httptest.NewRequest("GET", "/", nil)).Code }()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(time.Second)
+	if code := <-codes; code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d after the queue timeout, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	// A queued request is served once a slot frees up.
+	go func() { codes <- serve(s.server.Handler, httptest.NewRequest("GET", "/", nil)).Code }()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("expected queued request to be served, got %d", code)
+	}
+}
+
+func TestMaxConcurrentRequestsQueueCanceled(t *testing.T) {
+	h, entered, release := blocking()
+	defer close(release)
+	clk := clock.NewFake(time.Now())
+	s := New("", h, WithClock(clk), WithMaxConcurrentRequests(1), WithConcurrencyQueueTimeout(time.Second))
+
+	go serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	<-entered
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan *httptest.ResponseRecorder)
+	go func() { results <- serve(s.server.Handler, httptest.NewRequestWithContext(ctx, "GET", "/", nil)) }()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	w := <-results
+	if w.Body.Len() != 0 || w.Header().Get("Retry-After") != "" {
+		t.Errorf("expected a canceled request to be abandoned without a response, got %d %q", w.Code, w.Body)
+	}
+}
+
+func TestMaxConcurrentRequestsNotPositive(t *testing.T) {
+	s := New("", okHandler, WithMaxConcurrentRequests(-1))
+	if w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil)); w.Code != http.StatusOK {
+		t.Errorf("expected status %d without a limit, got %d", http.StatusOK, w.Code)
+	}
+}
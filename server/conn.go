@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// onConnContext registers fn to be called, in order, from the wrapped server's
+// ConnContext hook. This allows multiple options to attach per-connection
+// state without clobbering each other.
+func (s *Server) onConnContext(fn func(ctx context.Context, c net.Conn) context.Context) {
+	prev := s.server.ConnContext
+	if prev == nil {
+		s.server.ConnContext = fn
+		return
+	}
+	s.server.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		return fn(prev(ctx, c), c)
+	}
+}
+
+// onConnState registers fn to be called, in order, from the wrapped server's
+// ConnState hook.
+func (s *Server) onConnState(fn func(c net.Conn, state http.ConnState)) {
+	prev := s.server.ConnState
+	if prev == nil {
+		s.server.ConnState = fn
+		return
+	}
+	s.server.ConnState = func(c net.Conn, state http.ConnState) {
+		prev(c, state)
+		fn(c, state)
+	}
+}
+
+type connRequestsKey struct{}
+
+// WithMaxRequestsPerConn modifies the server to close keep-alive connections
+// after they have served n requests. The nth response on a connection is sent
+// with a "Connection: close" header, forcing the client to reconnect.
+//
+// This only has an effect when keep-alives are enabled, since otherwise each
+// connection serves a single request anyway. It also does not interact with
+// IdleTimeout: a connection may still be closed for idling before it reaches
+// n requests.
+func WithMaxRequestsPerConn(n int) Option {
+	return func(s *Server) *Server {
+		s.onConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, connRequestsKey{}, new(int64))
+		})
+		s.use("max_requests_per_conn", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if count, ok := r.Context().Value(connRequestsKey{}).(*int64); ok {
+					if atomic.AddInt64(count, 1) >= int64(n) {
+						w.Header().Set("Connection", "close")
+					}
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
+
+// CloseFunc adapts a function, such as a context.CancelFunc wrapped to return
+// nil, to an io.Closer for use with RegisterConn.
+type CloseFunc func() error
+
+// Close calls f.
+func (f CloseFunc) Close() error { return f() }
+
+// connRegistry tracks connections that the http.Server no longer manages, such
+// as hijacked WebSocket connections, so they can be closed on shutdown.
+type connRegistry struct {
+	mu      sync.Mutex
+	next    int
+	closers map[int]io.Closer
+}
+
+func newConnRegistry() *connRegistry {
+	return &connRegistry{closers: make(map[int]io.Closer)}
+}
+
+func (r *connRegistry) add(c io.Closer) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.next
+	r.next++
+	r.closers[id] = c
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.closers, id)
+	}
+}
+
+// closeAll closes and forgets every registered connection.
+func (r *connRegistry) closeAll() {
+	r.mu.Lock()
+	closers := r.closers
+	r.closers = make(map[int]io.Closer)
+	r.mu.Unlock()
+
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+type connRegistryKey struct{}
+
+// RegisterConn registers c to be closed when the server shuts down. ctx must be
+// derived from the context of a request served by a Server.
+//
+// http.Server.Shutdown neither waits for nor closes hijacked connections, such
+// as WebSockets, so handlers that hijack should register the connection (or a
+// CloseFunc that cancels its handling) to have it closed once the graceful
+// shutdown period is over. The returned function unregisters c, and should be
+// called once the connection is finished with. Hijack does all of this for
+// the common case of hijacking a request's own connection.
+//
+// If ctx did not come from a Server, RegisterConn does nothing.
+func RegisterConn(ctx context.Context, c io.Closer) (unregister func()) {
+	r, ok := ctx.Value(connRegistryKey{}).(*connRegistry)
+	if !ok {
+		return func() {}
+	}
+	return r.add(c)
+}
+
+// Hijack takes over the connection of a request served by a Server, as for an
+// HTTP/1.1 Upgrade to WebSocket, and registers it with RegisterConn so that it
+// is closed when the server shuts down. Closing the returned connection
+// unregisters it.
+//
+// The server's read and write deadlines are cleared, since a long-lived
+// upgraded connection would otherwise be cut off by the server's timeouts.
+// Handlers should set their own deadlines as they read and write.
+//
+// Hijack fails if the ResponseWriter does not support hijacking, as with
+// HTTP/2, or if the response has already been written.
+func Hijack(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	markHijacked(r.Context())
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	hc := &hijackedConn{Conn: conn}
+	hc.unregister = RegisterConn(r.Context(), conn)
+	return hc, brw, nil
+}
+
+// hijackedConn unregisters a connection taken over with Hijack when it is
+// closed.
+type hijackedConn struct {
+	net.Conn
+	unregister func()
+	once       sync.Once
+}
+
+func (c *hijackedConn) Close() error {
+	c.once.Do(c.unregister)
+	return c.Conn.Close()
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestServer starts an httptest server using the configuration of s.
+func newTestServer(t *testing.T, s *Server) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(s.server.Handler)
+	ts.Config.ConnContext = s.server.ConnContext
+	ts.Config.ConnState = s.server.ConnState
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestMaxRequestsPerConn(t *testing.T) {
+	s := New(":8080", okHandler, WithMaxRequestsPerConn(2))
+	ts := newTestServer(t, s)
+
+	for i, want := range []bool{false, true, false} {
+		resp, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.Close != want {
+			t.Errorf("request %d: expected close %t, got %t", i+1, want, resp.Close)
+		}
+	}
+}
+
+func TestRegisterConnClosedOnShutdown(t *testing.T) {
+	closed := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		RegisterConn(r.Context(), CloseFunc(func() error {
+			close(closed)
+			return conn.Close()
+		}))
+		conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n\r\n"))
+	})
+
+	s := New("", h, WithOutputWriter(io.Discard))
+	addr, errc := start(t, s)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	if _, err := br.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	select {
+	case <-closed:
+	default:
+		t.Fatal("expected the hijacked connection to be closed")
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadAll(br); err != nil {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestRegisterConnOutsideServer(t *testing.T) {
+	unregister := RegisterConn(context.Background(), CloseFunc(func() error { return nil }))
+	unregister()
+}
+
+func TestHijack(t *testing.T) {
+	hijacked := make(chan net.Conn, 1)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := Hijack(w, r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		brw.Flush()
+		hijacked <- conn
+	})
+
+	s := New("", h, WithReadTimeout(50*time.Millisecond), WithOutputWriter(io.Discard))
+	addr, errc := start(t, s)
+
+	dial := func() (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+		br := bufio.NewReader(conn)
+		if _, err := br.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		return conn, br
+	}
+	registered := func() int {
+		s.hijacked.mu.Lock()
+		defer s.hijacked.mu.Unlock()
+		return len(s.hijacked.closers)
+	}
+
+	// Closing a hijacked connection unregisters it.
+	dial()
+	(<-hijacked).Close()
+	if n := registered(); n != 0 {
+		t.Errorf("expected a closed connection to be unregistered, got %d registered", n)
+	}
+
+	// An open one is tracked, outlives the read timeout, and is closed on
+	// shutdown.
+	client, br := dial()
+	conn := <-hijacked
+	if n := registered(); n != 1 {
+		t.Fatalf("expected 1 registered connection, got %d", n)
+	}
+	time.Sleep(100 * time.Millisecond)
+	client.Write([]byte("ping"))
+	if _, err := io.ReadFull(conn, make([]byte, 4)); err != nil {
+		t.Fatalf("expected the connection to outlive the read timeout, got %v", err)
+	}
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadAll(br); err != nil {
+		t.Errorf("expected the connection to be closed on shutdown, got %v", err)
+	}
+}
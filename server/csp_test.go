@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSP(t *testing.T) {
+	var nonces []string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, CSPNonce(r.Context()))
+	})
+	s := New(":8080", h, WithCSP("default-src 'self'; script-src 'self' https://cdn.example.com"))
+
+	var headers []string
+	for i := 0; i < 2; i++ {
+		w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+		headers = append(headers, w.Header().Get("Content-Security-Policy"))
+	}
+
+	for i, nonce := range nonces {
+		if len(nonce) != 24 {
+			t.Fatalf("expected a 128-bit base64 nonce, got %q", nonce)
+		}
+		want := "default-src 'self'; script-src 'self' https://cdn.example.com 'nonce-" + nonce + "'"
+		if headers[i] != want {
+			t.Errorf("expected header %q, got %q", want, headers[i])
+		}
+	}
+	if nonces[0] == nonces[1] {
+		t.Error("expected a fresh nonce for every request")
+	}
+}
+
+func TestCSPWithoutScriptSrc(t *testing.T) {
+	got := cspWithNonce("default-src 'self';", "abc")
+	if want := "default-src 'self'; script-src 'nonce-abc'"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCSPNonceMissing(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if got := CSPNonce(r.Context()); got != "" {
+		t.Errorf("expected no nonce outside WithCSP, got %q", got)
+	}
+	if strings.Contains(cspWithNonce("", "abc"), ";") {
+		t.Error("expected an empty policy to produce a single directive")
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MaxJSONBytes is the maximum size of a request body accepted by DecodeJSON.
+const MaxJSONBytes = 1 << 20
+
+// StatusError is an error that carries the HTTP status code and a message
+// suitable for returning to the client.
+type StatusError struct {
+	// StatusCode is the HTTP status code that should be sent to the client.
+	StatusCode int
+
+	// Message is a human-readable description of the problem that is safe to
+	// send to the client.
+	Message string
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeJSON decodes the JSON body of r into dst. The request must have a
+// Content-Type of application/json, the body must contain exactly one JSON
+// value no larger than MaxJSONBytes, and it must not contain any fields that
+// are not present in dst.
+//
+// Any failure is returned as a *StatusError with an appropriate 4xx status
+// code and a message that can be sent to the client as-is.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		return &StatusError{
+			StatusCode: http.StatusUnsupportedMediaType,
+			Message:    "Content-Type must be application/json",
+			Err:        err,
+		}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxJSONBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return decodeError(err)
+		}
+		return &StatusError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body must contain a single JSON value",
+			Err:        err,
+		}
+	}
+
+	return nil
+}
+
+// decodeError translates an error from json.Decoder into a *StatusError.
+func decodeError(err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return &StatusError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body must not be empty",
+			Err:        err,
+		}
+	case errors.As(err, &syntaxErr):
+		return &StatusError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("request body contains malformed JSON at position %d", syntaxErr.Offset),
+			Err:        err,
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &StatusError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body contains malformed JSON",
+			Err:        err,
+		}
+	case errors.As(err, &typeErr):
+		msg := fmt.Sprintf("request body contains an invalid value at position %d", typeErr.Offset)
+		if typeErr.Field != "" {
+			msg = fmt.Sprintf("request body contains an invalid value for field %q", typeErr.Field)
+		}
+		return &StatusError{StatusCode: http.StatusBadRequest, Message: msg, Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &StatusError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body contains unknown field " + field,
+			Err:        err,
+		}
+	case errors.As(err, &maxErr):
+		return &StatusError{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Message:    fmt.Sprintf("request body must not be larger than %d bytes", maxErr.Limit),
+			Err:        err,
+		}
+	default:
+		return &StatusError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "request body could not be decoded",
+			Err:        err,
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := map[string]struct {
+		ct     string
+		body   string
+		status int
+	}{
+		"valid":          {"application/json", `{"id": 1, "name": "gear"}`, 0},
+		"charset":        {"application/json; charset=utf-8", `{"id": 1}`, 0},
+		"content type":   {"text/plain", `{"id": 1}`, http.StatusUnsupportedMediaType},
+		"no type":        {"", `{"id": 1}`, http.StatusUnsupportedMediaType},
+		"empty":          {"application/json", ``, http.StatusBadRequest},
+		"syntax":         {"application/json", `{"id": 1,}`, http.StatusBadRequest},
+		"truncated":      {"application/json", `{"id": 1`, http.StatusBadRequest},
+		"type mismatch":  {"application/json", `{"id": "one"}`, http.StatusBadRequest},
+		"unknown field":  {"application/json", `{"color": "red"}`, http.StatusBadRequest},
+		"multiple value": {"application/json", `{"id": 1}{"id": 2}`, http.StatusBadRequest},
+		"too large":      {"application/json", `{"name": "` + strings.Repeat("a", MaxJSONBytes) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for name, tt := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		if tt.ct != "" {
+			r.Header.Set("Content-Type", tt.ct)
+		}
+
+		var dst widget
+		err := DecodeJSON(httptest.NewRecorder(), r, &dst)
+		if tt.status == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", name, err)
+			}
+			continue
+		}
+
+		var serr *StatusError
+		if !errors.As(err, &serr) {
+			t.Errorf("%s: expected a *StatusError, got %v", name, err)
+			continue
+		}
+		if serr.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d (%s)", name, tt.status, serr.StatusCode, serr.Message)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/haleyrc/http/internal/describe"
+)
+
+// Describe returns a human-readable summary of the server's effective
+// configuration after all options were applied, such as its timeouts, limits,
+// TLS settings and installed middleware, for including in bug reports.
+func (s *Server) Describe() string {
+	return s.describe().String()
+}
+
+// DescribeJSON returns the same summary as Describe as a JSON object.
+func (s *Server) DescribeJSON() ([]byte, error) {
+	return json.Marshal(s.describe())
+}
+
+func (s *Server) describe() describe.Fields {
+	var fs describe.Fields
+	fs.Add("addr", s.addr)
+	fs.Add("read_timeout", s.server.ReadTimeout)
+	fs.Add("read_header_timeout", s.server.ReadHeaderTimeout)
+	fs.Add("write_timeout", s.server.WriteTimeout)
+	fs.Add("idle_timeout", s.server.IdleTimeout)
+	fs.Add("shutdown_timeout", s.shutdown)
+	fs.Add("drain_delay", s.drainDelay)
+	if s.drainAck != nil {
+		fs.Add("drain_ack", true)
+	}
+	fs.Add("max_header_bytes", s.server.MaxHeaderBytes)
+	fs.Add("http2_disabled", s.server.TLSNextProto != nil && len(s.server.TLSNextProto) == 0)
+
+	if cfg := s.server.TLSConfig; cfg != nil {
+		fs.Add("tls_min_version", describe.TLSVersion(cfg.MinVersion))
+		fs.Add("tls_next_protos", cfg.NextProtos)
+		fs.Add("tls_certificates", len(cfg.Certificates))
+		fs.Add("tls_client_hello_checks", len(s.helloChecks))
+	}
+
+	fs.Add("metrics", s.metrics != nil)
+	fs.Add("middleware", s.middlewareNames)
+	return fs
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	s := New(":8080", okHandler,
+		WithIdleTimeout(time.Minute),
+		WithRequestID(),
+		WithReadiness("/readyz"),
+		WithRejectLegacyTLS(),
+	)
+
+	got := s.Describe()
+	for _, want := range []string{
+		"addr: :8080\n",
+		"idle_timeout: 1m0s\n",
+		"tls_min_version: default\n",
+		"tls_client_hello_checks: 1\n",
+		"middleware: request_id, readiness(/readyz)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected description to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	b, err := s.DescribeJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var desc map[string]any
+	if err := json.Unmarshal(b, &desc); err != nil {
+		t.Fatal(err)
+	}
+	if desc["idle_timeout"] != "1m0s" {
+		t.Errorf("expected idle_timeout 1m0s, got %v", desc["idle_timeout"])
+	}
+	if mw, _ := desc["middleware"].([]any); len(mw) != 2 {
+		t.Errorf("expected 2 middleware, got %v", desc["middleware"])
+	}
+}
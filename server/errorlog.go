@@ -0,0 +1,90 @@
+package server
+
+import (
+	stdlog "log"
+	"strings"
+)
+
+// WithErrorLog modifies the server to use l for errors accepting connections,
+// unexpected behavior from handlers, and underlying file system errors. By
+// default, these are written to the standard logger.
+func WithErrorLog(l *stdlog.Logger) Option {
+	return func(s *Server) *Server {
+		s.server.ErrorLog = l
+		return s
+	}
+}
+
+// WithErrorLogger modifies the server to send its internal error log to l as
+// structured messages, rather than writing unstructured lines to the standard
+// logger.
+//
+// TLS handshake failures are logged as warnings with remote_addr and kind
+// fields, and reported to the server's MetricsRecorder, if one is configured,
+// so spikes in failures caused by scanners or misconfigured clients can be
+// alerted on.
+func WithErrorLogger(l Logger) Option {
+	return func(s *Server) *Server {
+		s.server.ErrorLog = stdlog.New(&errorLogAdapter{s: s, l: l}, "", 0)
+		return s
+	}
+}
+
+// errorLogAdapter is an io.Writer that receives lines from http.Server's
+// ErrorLog and forwards them to a Logger.
+type errorLogAdapter struct {
+	s *Server
+	l Logger
+}
+
+const tlsHandshakeErrorPrefix = "http: TLS handshake error from "
+
+func (a *errorLogAdapter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+
+	rest, ok := strings.CutPrefix(msg, tlsHandshakeErrorPrefix)
+	if !ok {
+		a.l.Error("http server error", "error", msg)
+		return len(p), nil
+	}
+
+	addr, cause, _ := strings.Cut(rest, ": ")
+	kind := classifyHandshakeError(cause)
+	a.l.Warn("tls handshake error", "remote_addr", addr, "kind", kind, "error", cause)
+	if a.s.metrics != nil {
+		a.s.metrics.RecordTLSHandshakeError(kind)
+	}
+	return len(p), nil
+}
+
+// handshakeErrorKinds maps substrings of TLS handshake errors to the kind
+// reported for them. The first match wins.
+var handshakeErrorKinds = []struct {
+	substr, kind string
+}{
+	{"client sent an HTTP request to an HTTPS server", "http_to_https"},
+	{ErrLegacyTLS.Error(), "legacy_tls"},
+	{ErrNoALPN.Error(), "no_alpn_protocol"},
+	{"unsupported versions", "unsupported_version"},
+	{"protocol version not supported", "unsupported_version"},
+	{"no cipher suite supported", "no_cipher_suite"},
+	{"no application protocol", "no_alpn_protocol"},
+	{"no certificates configured", "no_certificate"},
+	{"unrecognized name", "unrecognized_name"},
+	{"bad certificate", "bad_certificate"},
+	{"unknown certificate authority", "bad_certificate"},
+	{"i/o timeout", "timeout"},
+	{"connection reset", "connection_reset"},
+	{"EOF", "eof"},
+}
+
+// classifyHandshakeError returns a short description of the kind of TLS
+// handshake failure described by msg.
+func classifyHandshakeError(msg string) string {
+	for _, k := range handshakeErrorKinds {
+		if strings.Contains(msg, k.substr) {
+			return k.kind
+		}
+	}
+	return "other"
+}
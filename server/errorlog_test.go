@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type handshakeRecorder struct {
+	mu    sync.Mutex
+	kinds []string
+}
+
+func (r *handshakeRecorder) RecordTLSHandshakeError(kind string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kinds = append(r.kinds, kind)
+}
+
+func (r *handshakeRecorder) Kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.kinds...)
+}
+
+func TestClassifyHandshakeError(t *testing.T) {
+	tests := map[string]string{
+		"client sent an HTTP request to an HTTPS server":           "http_to_https",
+		"tls: client offered only unsupported versions: [302 301]": "unsupported_version",
+		"tls: no cipher suite supported by both client and server": "no_cipher_suite",
+		"EOF":             "eof",
+		"something weird": "other",
+	}
+	for msg, want := range tests {
+		if got := classifyHandshakeError(msg); got != want {
+			t.Errorf("%q: expected kind %q, got %q", msg, want, got)
+		}
+	}
+}
+
+func TestErrorLoggerHandshakeFailure(t *testing.T) {
+	l := newTestLogger()
+	rec := new(handshakeRecorder)
+	s := New("127.0.0.1:0", okHandler, WithErrorLogger(l), WithMetrics(rec))
+
+	// Borrow a test certificate from httptest.
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	ts.Close()
+	s.server.TLSConfig = &tls.Config{Certificates: ts.TLS.Certificates}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.ServeTLS(ln, "", "")
+	defer s.server.Close()
+
+	// A plaintext request to a TLS port fails the handshake.
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.Kinds()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if kinds := rec.Kinds(); len(kinds) != 1 || kinds[0] != "http_to_https" {
+		t.Errorf("expected kinds [http_to_https], got %v", kinds)
+	}
+	entries := l.Entries()
+	if len(entries) != 1 || !strings.HasPrefix(entries[0], "WARN tls handshake error [remote_addr 127.0.0.1:") {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Errors passed as the cause to an error responder by the built-in
+// middleware.
+var (
+	ErrURITooLong           = errors.New("server: request URI too long")
+	ErrTooManyHeaders       = errors.New("server: too many request header fields")
+	ErrMethodNotAllowed     = errors.New("server: method not allowed")
+	ErrHostNotAllowed       = errors.New("server: host not allowed")
+	ErrTooManyRequests      = errors.New("server: too many concurrent requests")
+	ErrEncodingNotAllowed   = errors.New("server: no acceptable content encoding")
+	ErrInvalidPath          = errors.New("server: invalid request path")
+	ErrNotFound             = errors.New("server: not found")
+	ErrBodyTooLarge         = errors.New("server: request body too large")
+	ErrForbidden            = errors.New("server: forbidden")
+	ErrUnauthorized         = errors.New("server: unauthorized")
+	ErrIdempotencyKeyReused = errors.New("server: idempotency key reused with a different request")
+)
+
+// WithErrorResponder modifies the server to write the error responses
+// generated by its built-in middleware and helpers, such as a 405 from
+// WithAllowedMethods or a 503 from WithMaxConcurrentRequests, with fn instead
+// of http.Error, so every error can share one format such as a JSON envelope.
+// status is the HTTP status to send, and cause describes the failure, and is
+// usually one of the errors defined by this package.
+//
+// Any headers a middleware sets for the error, such as Allow or Retry-After,
+// are set before fn is called. The built-in middleware falls back to
+// http.Error when used outside of a Server configured with a responder.
+func WithErrorResponder(fn func(w http.ResponseWriter, r *http.Request, status int, cause error)) Option {
+	return func(s *Server) *Server {
+		s.errorResponder = fn
+		return s
+	}
+}
+
+type errorResponderKey struct{}
+
+// withErrorResponder wraps h to make the server's error responder, if any,
+// available to writeError.
+func (s *Server) withErrorResponder(h http.Handler) http.Handler {
+	if s.errorResponder == nil {
+		return h
+	}
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	fn := s.errorResponder
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), errorResponderKey{}, fn)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeError writes an error response with the given status using the error
+// responder of the server handling r, or http.Error if there is none.
+func writeError(w http.ResponseWriter, r *http.Request, status int, cause error) {
+	if fn, ok := r.Context().Value(errorResponderKey{}).(func(http.ResponseWriter, *http.Request, int, error)); ok {
+		fn(w, r, status, cause)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
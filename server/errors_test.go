@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type errorEnvelope struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+func jsonErrors(w http.ResponseWriter, r *http.Request, status int, cause error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorEnvelope{Status: status, Error: cause.Error()})
+}
+
+func TestErrorResponder(t *testing.T) {
+	s := New("", okHandler,
+		WithErrorResponder(jsonErrors),
+		WithAllowedHosts("example.com"),
+		WithAllowedMethods("GET"),
+		WithMaxURLLength(32),
+	)
+
+	for _, tt := range []struct {
+		name   string
+		req    *http.Request
+		status int
+		cause  error
+	}{
+		{"host", httptest.NewRequest("GET", "http://evil.com/", nil), http.StatusMisdirectedRequest, ErrHostNotAllowed},
+		{"method", httptest.NewRequest("POST", "http://example.com/", nil), http.StatusMethodNotAllowed, ErrMethodNotAllowed},
+		{"url", httptest.NewRequest("GET", "http://example.com/a/very/long/path/indeed/yes", nil), http.StatusRequestURITooLong, ErrURITooLong},
+	} {
+		w := serve(s.server.Handler, tt.req)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+		var env errorEnvelope
+		if err := json.NewDecoder(w.Body).Decode(&env); err != nil {
+			t.Errorf("%s: expected a JSON envelope: %v", tt.name, err)
+			continue
+		}
+		if env.Status != tt.status || env.Error != tt.cause.Error() {
+			t.Errorf("%s: expected envelope for %v, got %+v", tt.name, tt.cause, env)
+		}
+	}
+
+	if got := serve(s.server.Handler, httptest.NewRequest("POST", "http://example.com/", nil)).Header().Get("Allow"); got != "GET" {
+		t.Errorf("expected the Allow header to be kept, got %q", got)
+	}
+}
+
+func TestErrorResponderStaticNotFound(t *testing.T) {
+	s := New("", StaticFS(staticFS, StaticOptions{}), WithErrorResponder(jsonErrors))
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/missing.txt", nil))
+	var env errorEnvelope
+	if err := json.NewDecoder(w.Body).Decode(&env); err != nil {
+		t.Fatalf("expected a JSON envelope: %v", err)
+	}
+	if env.Status != http.StatusNotFound || env.Error != ErrNotFound.Error() {
+		t.Errorf("expected envelope for %v, got %+v", ErrNotFound, env)
+	}
+}
+
+func TestErrorResponderRespond(t *testing.T) {
+	var got error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Respond(w, r, http.StatusOK, func() {})
+	})
+	s := New("", h, WithErrorResponder(func(w http.ResponseWriter, r *http.Request, status int, cause error) {
+		got = cause
+		w.WriteHeader(status)
+	}))
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	var unsupported *json.UnsupportedTypeError
+	if !errors.As(got, &unsupported) {
+		t.Errorf("expected the encoding error as the cause, got %v", got)
+	}
+}
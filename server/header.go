@@ -0,0 +1,25 @@
+package server
+
+import "net/http"
+
+// WithServerHeader modifies the server to send value as the Server header of
+// every response. If value is empty, any Server header set by middleware added
+// before this option is removed instead, so none is sent unless a handler
+// sets one.
+//
+// A Server header set by a handler always takes precedence.
+func WithServerHeader(value string) Option {
+	return func(s *Server) *Server {
+		s.use("server_header", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if value == "" {
+					w.Header().Del("Server")
+				} else {
+					w.Header().Set("Server", value)
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
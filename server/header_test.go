@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerHeader(t *testing.T) {
+	s := New("", okHandler, WithServerHeader("acme"))
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Server"); got != "acme" {
+		t.Errorf("expected Server acme, got %q", got)
+	}
+}
+
+func TestServerHeaderSuppress(t *testing.T) {
+	leaky := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", "nginx/1.25.3")
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := New("", okHandler, func(s *Server) *Server { s.use("leaky", leaky); return s }, WithServerHeader(""))
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if got, ok := w.Header()["Server"]; ok {
+		t.Errorf("expected no Server header, got %q", got)
+	}
+}
+
+func TestServerHeaderHandlerOverride(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "custom")
+	})
+	s := New("", h, WithServerHeader("acme"))
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Server"); got != "custom" {
+		t.Errorf("expected the handler's Server header, got %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AllowedHosts returns a middleware that rejects any request whose Host is not
+// in hosts with a 421 Misdirected Request. This protects handlers that build
+// absolute URLs from the Host header, which is attacker-controlled, against
+// cache poisoning and link hijacking.
+//
+// Entries are matched case-insensitively against the request's host, ignoring
+// any port unless the entry itself includes one. An entry of the form
+// "*.example.com" matches any subdomain of example.com, but not example.com
+// itself.
+func AllowedHosts(hosts []string) func(http.Handler) http.Handler {
+	allowed := make([]string, len(hosts))
+	for i, h := range hosts {
+		allowed[i] = normalizeHost(h)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hostAllowed(allowed, r.Host) {
+				writeError(w, r, http.StatusMisdirectedRequest, ErrHostNotAllowed)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithAllowedHosts modifies the server to reject requests for any host not in
+// hosts. See AllowedHosts for details.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(s *Server) *Server {
+		s.use("allowed_hosts", AllowedHosts(hosts))
+		return s
+	}
+}
+
+func hostAllowed(allowed []string, hostport string) bool {
+	hostport = normalizeHost(hostport)
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+
+	for _, a := range allowed {
+		if a == host || a == hostport {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(a, "*"); ok && strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost lowercases h and removes any trailing dot from the host name,
+// as well as the brackets from a bare IPv6 address.
+func normalizeHost(h string) string {
+	h = strings.ToLower(h)
+	if host, port, err := net.SplitHostPort(h); err == nil {
+		return net.JoinHostPort(strings.TrimSuffix(host, "."), port)
+	}
+	if strings.HasPrefix(h, "[") && strings.HasSuffix(h, "]") {
+		return h[1 : len(h)-1]
+	}
+	return strings.TrimSuffix(h, ".")
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	h := AllowedHosts([]string{"example.com", "*.api.example.com", "localhost:8080", "[::1]"})(okHandler)
+
+	tests := map[string]int{
+		"example.com":         http.StatusOK,
+		"EXAMPLE.com.":        http.StatusOK,
+		"example.com:443":     http.StatusOK,
+		"v1.api.example.com":  http.StatusOK,
+		"a.b.api.example.com": http.StatusOK,
+		"localhost:8080":      http.StatusOK,
+		"[::1]:8443":          http.StatusOK,
+		"api.example.com":     http.StatusMisdirectedRequest,
+		"evil.com":            http.StatusMisdirectedRequest,
+		"example.com.evil.io": http.StatusMisdirectedRequest,
+		"evilapi.example.com": http.StatusMisdirectedRequest,
+		"localhost:9090":      http.StatusMisdirectedRequest,
+		"":                    http.StatusMisdirectedRequest,
+	}
+	for host, want := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = host
+		if got := serve(h, r).Code; got != want {
+			t.Errorf("%q: expected status %d, got %d", host, want, got)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the key Idempotency
+// uses to recognize a retried request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set to "true" on responses Idempotency replays
+// from its store.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// DefaultIdempotencyMaxBodyBytes is the largest response body Idempotency
+// keeps when IdempotencyOptions.MaxBodyBytes is not set.
+const DefaultIdempotencyMaxBodyBytes = 1 << 20
+
+// StoredResponse is a response kept by an IdempotencyStore.
+type StoredResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// RequestHash is the SHA-256 hash of the body of the request the
+	// response was written for, in hex.
+	RequestHash string
+}
+
+// IdempotencyStore persists the responses served by Idempotency. It must be
+// safe for concurrent use.
+type IdempotencyStore interface {
+	// Get returns the response stored under key, and whether there was one
+	// that has not expired.
+	Get(ctx context.Context, key string) (*StoredResponse, bool, error)
+
+	// Set stores resp under key until ttl has passed.
+	Set(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error
+}
+
+// IdempotencyOptions configures Idempotency.
+type IdempotencyOptions struct {
+	// TTL is how long a response is kept for replay.
+	TTL time.Duration
+
+	// MaxBodyBytes is the largest response body that is kept. Responses with
+	// larger bodies are sent as usual but not stored, so a retry runs the
+	// handler again. It defaults to DefaultIdempotencyMaxBodyBytes.
+	MaxBodyBytes int
+
+	// Scope, if set, returns an identifier for the caller making r, such as
+	// the ID of the authenticated user, and keys are scoped to it, so a
+	// caller can only ever be replayed its own responses. Without Scope, a
+	// key is shared by every caller, and anyone who knows or guesses it gets
+	// the stored response, cookies included; servers with more than one
+	// caller should set it.
+	Scope func(r *http.Request) string
+}
+
+// Idempotency returns a middleware that makes POST, PUT, PATCH and DELETE
+// requests carrying an Idempotency-Key header safe to retry. The first
+// request with a key is served as usual, and its response is kept in store
+// for opts.TTL; a request repeating the key within that time gets the stored
+// response, with an Idempotent-Replayed header, without the handler running
+// again. Requests without the header, and those using other methods, are
+// served as usual.
+//
+// Keys are scoped to the method and path of the request, and to the caller
+// returned by opts.Scope, so the same key sent to different endpoints does
+// not collide. Responses with a 5xx status are not stored, so a request that
+// failed on the server can be retried for real. A request repeating a key
+// with a different body is rejected with 422 Unprocessable Entity and
+// ErrIdempotencyKeyReused.
+//
+// A request that arrives while another with the same key is still being
+// served waits for it to finish, or for its own context to be done, and then
+// gets its response. This serialization only covers requests within one
+// process; instances sharing a store can each run the handler once for a key
+// sent to both at the same time.
+//
+// If the store fails to look up a key, the request is rejected with 500
+// Internal Server Error rather than risk running the handler twice. A failure
+// to store a response, which has already been sent by then, is logged to the
+// request's logger.
+func Idempotency(store IdempotencyStore, opts IdempotencyOptions) func(http.Handler) http.Handler {
+	maxBody := opts.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = DefaultIdempotencyMaxBodyBytes
+	}
+
+	var (
+		mu       sync.Mutex
+		inflight = make(map[string]chan struct{})
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			switch {
+			case key == "":
+				next.ServeHTTP(w, r)
+				return
+			case r.Method != http.MethodPost && r.Method != http.MethodPut &&
+				r.Method != http.MethodPatch && r.Method != http.MethodDelete:
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = r.Method + " " + r.URL.Path + " " + key
+			if opts.Scope != nil {
+				key = opts.Scope(r) + " " + key
+			}
+			ctx := r.Context()
+
+			var done chan struct{}
+			for {
+				mu.Lock()
+				var busy bool
+				done, busy = inflight[key]
+				if !busy {
+					done = make(chan struct{})
+					inflight[key] = done
+				}
+				mu.Unlock()
+				if !busy {
+					break
+				}
+				select {
+				case <-done:
+				case <-ctx.Done():
+					return
+				}
+			}
+			defer func() {
+				mu.Lock()
+				delete(inflight, key)
+				mu.Unlock()
+				close(done)
+			}()
+
+			stored, ok, err := store.Get(ctx, key)
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, fmt.Errorf("server: idempotency store: %w", err))
+				return
+			}
+			if ok {
+				sum, err := hashBody(r.Body)
+				if err != nil {
+					writeError(w, r, http.StatusBadRequest, err)
+					return
+				}
+				if sum != stored.RequestHash {
+					writeError(w, r, http.StatusUnprocessableEntity, ErrIdempotencyKeyReused)
+					return
+				}
+				replay(w, stored)
+				return
+			}
+
+			h := sha256.New()
+			if r.Body != nil {
+				body := r.Body
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(body, h), body}
+			}
+			rec := &recordingWriter{ResponseWriter: w, max: maxBody}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			if rec.status >= 500 || rec.overflow {
+				return
+			}
+			// The hash must cover the whole body, whether or not the
+			// handler read all of it.
+			if r.Body != nil {
+				if _, err := io.Copy(io.Discard, r.Body); err != nil {
+					return
+				}
+			}
+			err = store.Set(context.WithoutCancel(ctx), key, &StoredResponse{
+				StatusCode:  rec.status,
+				Header:      rec.header,
+				Body:        rec.body.Bytes(),
+				RequestHash: hex.EncodeToString(h.Sum(nil)),
+			}, opts.TTL)
+			if err != nil {
+				LoggerFromContext(ctx).Error("idempotency store failed", "error", err)
+			}
+		})
+	}
+}
+
+// WithIdempotency modifies the server to replay the responses to requests
+// repeating an Idempotency-Key. See Idempotency.
+func WithIdempotency(store IdempotencyStore, opts IdempotencyOptions) Option {
+	return func(s *Server) *Server {
+		s.use("idempotency", Idempotency(store, opts))
+		return s
+	}
+}
+
+// hashBody returns the SHA-256 hash of the rest of body, in hex.
+func hashBody(body io.Reader) (string, error) {
+	h := sha256.New()
+	if body != nil {
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay writes a stored response.
+func replay(w http.ResponseWriter, stored *StoredResponse) {
+	for k, v := range stored.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.Body)
+}
+
+// recordingWriter keeps a copy of the response written through it, up to max
+// bytes of body.
+type recordingWriter struct {
+	http.ResponseWriter
+	max      int
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 {
+		w.status = code
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case w.overflow:
+	case w.body.Len()+len(p) > w.max:
+		w.overflow = true
+		w.body = bytes.Buffer{}
+	default:
+		w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush flushes the underlying writer, if it supports flushing.
+func (w *recordingWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that keeps responses in
+// memory, for single-instance servers and tests. Expired responses are
+// removed as new ones are stored.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryIdempotencyEntry
+	now     func() time.Time
+}
+
+type memoryIdempotencyEntry struct {
+	resp    *StoredResponse
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]memoryIdempotencyEntry), now: time.Now}
+}
+
+// Get implements IdempotencyStore.
+func (m *MemoryIdempotencyStore) Get(ctx context.Context, key string) (*StoredResponse, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || !m.now().Before(e.expires) {
+		return nil, false, nil
+	}
+	return e.resp, true, nil
+}
+
+// Set implements IdempotencyStore.
+func (m *MemoryIdempotencyStore) Set(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for k, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = memoryIdempotencyEntry{resp: resp, expires: now.Add(ttl)}
+	return nil
+}
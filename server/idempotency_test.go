@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingSetStore is an IdempotencyStore whose Set always fails.
+type failingSetStore struct {
+	*MemoryIdempotencyStore
+}
+
+func (failingSetStore) Set(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+// counting returns a handler that responds 201 with the number of times it
+// has been called.
+func counting(calls *atomic.Int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("X-Order", strconv.FormatInt(n, 10))
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "order "+strconv.FormatInt(n, 10))
+	})
+}
+
+func idempotentRequest(key string) *http.Request {
+	return idempotentRequestWithBody(key, "")
+}
+
+func idempotentRequestWithBody(key, body string) *http.Request {
+	r := httptest.NewRequest("POST", "/orders", strings.NewReader(body))
+	r.Header.Set(IdempotencyKeyHeader, key)
+	return r
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls atomic.Int64
+	h := Idempotency(NewMemoryIdempotencyStore(), IdempotencyOptions{TTL: time.Hour})(counting(&calls))
+
+	first := serve(h, idempotentRequest("abc"))
+	if first.Code != http.StatusCreated || first.Body.String() != "order 1" {
+		t.Fatalf("first: got %d %q", first.Code, first.Body)
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("first response marked as replayed")
+	}
+
+	replayed := serve(h, idempotentRequest("abc"))
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != "order 1" {
+		t.Fatalf("replay: got %d %q", replayed.Code, replayed.Body)
+	}
+	if got := replayed.Header().Get("X-Order"); got != "1" {
+		t.Errorf("replay: X-Order = %q, want 1", got)
+	}
+	if replayed.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("replay not marked as replayed")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+
+	if other := serve(h, idempotentRequest("def")); other.Body.String() != "order 2" {
+		t.Errorf("new key: got %q, want order 2", other.Body)
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	var calls atomic.Int64
+	h := Idempotency(NewMemoryIdempotencyStore(), IdempotencyOptions{TTL: time.Hour})(counting(&calls))
+
+	serve(h, idempotentRequest("abc"))
+
+	other := httptest.NewRequest("POST", "/refunds", nil)
+	other.Header.Set(IdempotencyKeyHeader, "abc")
+	serve(h, other)
+
+	get := httptest.NewRequest("GET", "/orders", nil)
+	get.Header.Set(IdempotencyKeyHeader, "abc")
+	serve(h, get)
+
+	serve(h, httptest.NewRequest("POST", "/orders", nil))
+
+	if n := calls.Load(); n != 4 {
+		t.Errorf("handler ran %d times, want 4", n)
+	}
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	var calls atomic.Int64
+	store := NewMemoryIdempotencyStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	h := Idempotency(store, IdempotencyOptions{TTL: time.Minute})(counting(&calls))
+
+	serve(h, idempotentRequest("abc"))
+	now = now.Add(time.Minute)
+	if got := serve(h, idempotentRequest("abc")); got.Body.String() != "order 2" {
+		t.Errorf("after expiry: got %q, want order 2", got.Body)
+	}
+}
+
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	var calls atomic.Int64
+	h := Idempotency(NewMemoryIdempotencyStore(), IdempotencyOptions{TTL: time.Hour})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	serve(h, idempotentRequest("abc"))
+	serve(h, idempotentRequest("abc"))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	var calls atomic.Int64
+	block, entered, release := blocking()
+	h := Idempotency(NewMemoryIdempotencyStore(), IdempotencyOptions{TTL: time.Hour})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		block.ServeHTTP(w, r)
+	}))
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = serve(h, idempotentRequest("abc"))
+	}()
+	<-entered
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[1] = serve(h, idempotentRequest("abc"))
+	}()
+	select {
+	case <-entered:
+		t.Fatal("duplicate request ran the handler while the first was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+	if results[1].Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("duplicate did not get the replayed response")
+	}
+	if results[0].Code != results[1].Code {
+		t.Errorf("statuses differ: %d and %d", results[0].Code, results[1].Code)
+	}
+}
+
+func TestIdempotencyKeyReused(t *testing.T) {
+	var calls atomic.Int64
+	h := Idempotency(NewMemoryIdempotencyStore(), IdempotencyOptions{TTL: time.Hour})(counting(&calls))
+
+	serve(h, idempotentRequestWithBody("abc", `{"amount":10}`))
+	if got := serve(h, idempotentRequestWithBody("abc", `{"amount":10}`)); got.Body.String() != "order 1" {
+		t.Errorf("same body: got %q, want the replayed order 1", got.Body)
+	}
+	if got := serve(h, idempotentRequestWithBody("abc", `{"amount":99}`)); got.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: got status %d, want %d", got.Code, http.StatusUnprocessableEntity)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyCallerScope(t *testing.T) {
+	var calls atomic.Int64
+	h := Idempotency(NewMemoryIdempotencyStore(), IdempotencyOptions{
+		TTL:   time.Hour,
+		Scope: func(r *http.Request) string { return r.Header.Get("X-User") },
+	})(counting(&calls))
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		r := idempotentRequest("abc")
+		r.Header.Set("X-User", user)
+		serve(h, r)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyLargeResponseNotStored(t *testing.T) {
+	var calls atomic.Int64
+	h := Idempotency(NewMemoryIdempotencyStore(), IdempotencyOptions{TTL: time.Hour, MaxBodyBytes: 4})(counting(&calls))
+
+	first := serve(h, idempotentRequest("abc"))
+	if first.Body.String() != "order 1" {
+		t.Fatalf("first: got %q, want the full response", first.Body)
+	}
+	serve(h, idempotentRequest("abc"))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyStoreSetError(t *testing.T) {
+	var calls atomic.Int64
+	logger := newTestLogger()
+	store := failingSetStore{NewMemoryIdempotencyStore()}
+	s := New("", counting(&calls), WithRequestLogger(logger), WithIdempotency(store, IdempotencyOptions{TTL: time.Hour}))
+
+	w := serve(s.server.Handler, idempotentRequest("abc"))
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	entries := logger.Entries()
+	if len(entries) != 1 || !strings.Contains(entries[0], "store unavailable") {
+		t.Errorf("expected the store error to be logged, got %v", entries)
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// WithListenBacklog modifies the server to allow up to n connections to wait
+// in the kernel's accept queue of a TCP listener it creates, which can prevent
+// dropped connections during bursts. By default Go uses the system maximum,
+// net.core.somaxconn on Linux, which is often as low as 128 or 4096.
+//
+// The kernel clamps n to that maximum, so raising the backlog beyond it also
+// requires raising the system setting. The backlog is applied by listening
+// again on the bound socket, which Linux and the BSDs, including macOS,
+// support; on other platforms, and for Unix sockets and listeners passed to
+// Serve, it has no effect.
+func WithListenBacklog(n int) Option {
+	return func(s *Server) *Server {
+		s.listenBacklog = n
+		return s
+	}
+}
+
+// WithReusePort modifies the server to set SO_REUSEPORT on the TCP listener it
+// creates, so several processes, each running a server with this option, can
+// bind the same port and have the kernel distribute connections between them,
+// scaling across cores without a load balancer. All the processes must set
+// the option, and on Linux they must run as the same user.
+//
+// SO_REUSEPORT is supported on Linux and the BSDs, including macOS. On other
+// platforms, ListenAndServe fails with an error wrapping
+// ErrReusePortUnsupported. It has no effect on Unix sockets or on listeners
+// passed to Serve.
+func WithReusePort() Option {
+	return func(s *Server) *Server {
+		s.reusePort = true
+		return s
+	}
+}
+
+// listenTCP binds a TCP listener on addr with the configured socket options.
+func (s *Server) listenTCP(addr string) (net.Listener, error) {
+	var lc net.ListenConfig
+	if s.reusePort {
+		lc.Control = reusePortControl
+	}
+	ln, err := lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.listenBacklog > 0 {
+		if err := setBacklog(ln, s.listenBacklog); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// WithGracefulListenerClose modifies the server to stop accepting connections
+// as soon as it receives a shutdown signal, or its context is done, rather
+// than when the graceful shutdown begins after the drain delay. Requests on
+// connections that were already accepted, including new requests on
+// keep-alive connections, are still served until the shutdown completes, but
+// new connections are refused from the moment the signal arrives.
+//
+// This gives a strict guarantee that no new connections are accepted after
+// the signal, at the cost of the drain delay set with WithDrainDelay: a load
+// balancer that has not yet noticed the failing readiness endpoint gets
+// refused connections instead of being served. It is most useful without a
+// drain delay, or when the server is not behind a load balancer at all.
+func WithGracefulListenerClose() Option {
+	return func(s *Server) *Server {
+		s.gracefulListenerClose = true
+		return s
+	}
+}
+
+// gracefulListener is a listener whose accept side can be closed, refusing new
+// connections, without making the http.Server's accept loop exit before it is
+// shut down.
+type gracefulListener struct {
+	net.Listener
+
+	stopOnce  sync.Once
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newGracefulListener(ln net.Listener) *gracefulListener {
+	return &gracefulListener{
+		Listener: ln,
+		stopped:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Accept waits for the next connection. Once the listener has stopped
+// accepting, it blocks until Close is called, so the accept loop exits only
+// when the http.Server is shutting down.
+func (l *gracefulListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		select {
+		case <-l.stopped:
+			<-l.closed
+			return nil, net.ErrClosed
+		default:
+		}
+	}
+	return c, err
+}
+
+// stopAccepting closes the underlying listener so that new connections are
+// refused.
+func (l *gracefulListener) stopAccepting() {
+	l.stopOnce.Do(func() {
+		close(l.stopped)
+		l.Listener.Close()
+	})
+}
+
+func (l *gracefulListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	l.stopAccepting()
+	return nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGracefulListenerClose(t *testing.T) {
+	h, entered, release := blocking()
+	s := New("", h,
+		WithOutputWriter(io.Discard),
+		WithGracefulListenerClose(),
+		WithDrainDelay(time.Minute),
+	)
+	addr, errc := start(t, s)
+
+	inflight := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			inflight <- 0
+			return
+		}
+		resp.Body.Close()
+		inflight <- resp.StatusCode
+	}()
+	<-entered
+
+	s.signals <- syscall.SIGTERM
+
+	// The drain delay is still running, but new connections are refused.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("expected new connections to be refused after the signal")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	if got := <-inflight; got != http.StatusOK {
+		t.Errorf("expected the in-flight request to finish, got status %d", got)
+	}
+
+	// End the drain delay.
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+}
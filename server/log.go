@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Logger is a minimal structured logger. Key-value pairs are provided as
+// alternating keys and values, in the style of log/slog.
+type Logger interface {
+	Info(msg string, keyvals ...any)
+	Warn(msg string, keyvals ...any)
+	Error(msg string, keyvals ...any)
+
+	// With returns a child logger that includes the provided key-value pairs
+	// in every message.
+	With(keyvals ...any) Logger
+}
+
+// NopLogger returns a Logger that discards everything.
+func NopLogger() Logger { return nopLogger{} }
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+func (nopLogger) With(...any) Logger   { return nopLogger{} }
+
+// SlogLogger adapts a *slog.Logger to the Logger interface.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Info(msg string, keyvals ...any)  { s.l.Info(msg, keyvals...) }
+func (s slogLogger) Warn(msg string, keyvals ...any)  { s.l.Warn(msg, keyvals...) }
+func (s slogLogger) Error(msg string, keyvals ...any) { s.l.Error(msg, keyvals...) }
+func (s slogLogger) With(keyvals ...any) Logger       { return slogLogger{s.l.With(keyvals...)} }
+
+type loggerKey struct{}
+
+// loggerSlotKey holds a *Logger that WithRequestLogger fills in with the
+// logger it derives, so that middleware installed before it can use it once
+// the handler has returned.
+type loggerSlotKey struct{}
+
+// withLoggerSlot returns a copy of r with a slot in its context that will
+// hold the logger derived by WithRequestLogger, if it runs.
+func withLoggerSlot(r *http.Request) (*http.Request, *Logger) {
+	p := new(Logger)
+	return r.WithContext(context.WithValue(r.Context(), loggerSlotKey{}, p)), p
+}
+
+// LoggerFromContext returns the request-scoped logger stored in ctx by
+// WithRequestLogger. If there is no logger in the context, a no-op logger is
+// returned, so the result is always safe to use.
+func LoggerFromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return l
+	}
+	return nopLogger{}
+}
+
+// WithRequestLogger modifies the server to derive a child of base for every
+// request, annotated with the request method, path, and ID, and store it in the
+// request context where it can be retrieved with LoggerFromContext.
+//
+// If base is nil, handlers receive a no-op logger.
+func WithRequestLogger(base Logger) Option {
+	if base == nil {
+		base = nopLogger{}
+	}
+	return func(s *Server) *Server {
+		s.use("request_logger", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				l := base.With(
+					"method", r.Method,
+					"path", r.URL.Path,
+					"request_id", requestID(r),
+				)
+				if p, ok := r.Context().Value(loggerSlotKey{}).(*Logger); ok {
+					*p = l
+				}
+				ctx := context.WithValue(r.Context(), loggerKey{}, l)
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+		return s
+	}
+}
+
+// WithSlowRequestLog modifies the server to log a warning to l for every
+// request that takes longer than threshold to handle. Slow requests are not
+// otherwise affected.
+//
+// The warning includes the method, path, and elapsed time, as well as the
+// route pattern if the request was routed by an http.ServeMux.
+func WithSlowRequestLog(threshold time.Duration, l Logger) Option {
+	if l == nil {
+		l = nopLogger{}
+	}
+	return func(s *Server) *Server {
+		s.use("slow_request_log", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r, route := withRoute(r)
+				start := s.clock.Now()
+				next.ServeHTTP(w, r)
+				elapsed := s.clock.Now().Sub(start)
+				if elapsed <= threshold {
+					return
+				}
+				kv := []any{"method", r.Method, "path", r.URL.Path, "elapsed", elapsed}
+				if *route != "" {
+					kv = append(kv, "route", *route)
+				}
+				l.Warn("slow request", kv...)
+			})
+		})
+		return s
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+// testLogger records every message logged through it or any of its children.
+type testLogger struct {
+	mu      *sync.Mutex
+	entries *[]string
+	fields  []any
+}
+
+func newTestLogger() *testLogger {
+	return &testLogger{mu: new(sync.Mutex), entries: new([]string)}
+}
+
+func (l *testLogger) log(level, msg string, keyvals ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kv := append(append([]any{}, l.fields...), keyvals...)
+	*l.entries = append(*l.entries, fmt.Sprint(level, " ", msg, " ", kv))
+}
+
+func (l *testLogger) Info(msg string, keyvals ...any)  { l.log("INFO", msg, keyvals...) }
+func (l *testLogger) Warn(msg string, keyvals ...any)  { l.log("WARN", msg, keyvals...) }
+func (l *testLogger) Error(msg string, keyvals ...any) { l.log("ERROR", msg, keyvals...) }
+
+func (l *testLogger) With(keyvals ...any) Logger {
+	return &testLogger{
+		mu:      l.mu,
+		entries: l.entries,
+		fields:  append(append([]any{}, l.fields...), keyvals...),
+	}
+}
+
+func (l *testLogger) Entries() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, *l.entries...)
+}
+
+func TestRequestLogger(t *testing.T) {
+	base := newTestLogger()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LoggerFromContext(r.Context()).Info("hello")
+	})
+	s := New(":8080", h, WithRequestID(), WithRequestLogger(base))
+
+	r := httptest.NewRequest("GET", "/widgets", nil)
+	r.Header.Set(RequestIDHeader, "abc123")
+	serve(s.server.Handler, r)
+
+	entries := base.Entries()
+	want := "INFO hello [method GET path /widgets request_id abc123]"
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("expected entries [%s], got %v", want, entries)
+	}
+}
+
+func TestLoggerFromContextDefault(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, ok := LoggerFromContext(r.Context()).(nopLogger); !ok {
+		t.Error("expected a no-op logger")
+	}
+}
+
+func TestSlowRequestLog(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {
+		clk.Advance(5 * time.Millisecond)
+	})
+	mux.HandleFunc("GET /slow/{id}", func(w http.ResponseWriter, r *http.Request) {
+		clk.Advance(20 * time.Millisecond)
+	})
+
+	l := newTestLogger()
+	s := New(":8080", mux, WithSlowRequestLog(10*time.Millisecond, l), WithRequestID(), WithClock(clk))
+
+	serve(s.server.Handler, httptest.NewRequest("GET", "/fast", nil))
+	serve(s.server.Handler, httptest.NewRequest("GET", "/slow/1", nil))
+
+	entries := l.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %v", entries)
+	}
+	if want := "WARN slow request [method GET path /slow/1 elapsed 20ms route GET /slow/{id}]"; entries[0] != want {
+		t.Errorf("expected entry %q, got %q", want, entries[0])
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// MetricsRecorder receives metrics from the server. Implementations must be
+// safe for concurrent use.
+type MetricsRecorder interface {
+	// RecordTLSHandshakeError is called for every failed TLS handshake, with
+	// a short, low-cardinality description of the failure such as
+	// "unsupported_version" or "http_to_https".
+	RecordTLSHandshakeError(kind string)
+}
+
+// RequestRecorder is implemented by a MetricsRecorder that also records a
+// measurement for every request served.
+type RequestRecorder interface {
+	// RecordRequest is called once each request has been handled, with the
+	// ServeMux pattern that matched it, or "" if it was not routed by a
+	// ServeMux, and the status code sent.
+	RecordRequest(method, route string, status int, duration time.Duration)
+}
+
+// SizeRecorder is implemented by a MetricsRecorder that also records the size
+// of every request and response body, for capacity planning.
+type SizeRecorder interface {
+	// ObserveSizes is called once each request has been handled, with the
+	// route as for RecordRequest and the number of body bytes actually read
+	// from the request and written in the response. Chunked bodies, and
+	// request bodies the handler did not read in full, are counted by what
+	// was transferred rather than by any Content-Length.
+	ObserveSizes(route string, reqBytes, respBytes int64)
+}
+
+// WithMetrics modifies the server to report metrics to rec. If rec implements
+// RequestRecorder or SizeRecorder, every request that reaches the point in the
+// middleware chain where this option was applied is recorded too.
+func WithMetrics(rec MetricsRecorder) Option {
+	return func(s *Server) *Server {
+		s.metrics = rec
+		rr, _ := rec.(RequestRecorder)
+		sr, _ := rec.(SizeRecorder)
+		if rr == nil && sr == nil {
+			return s
+		}
+		s.use("metrics", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r, route := withRoute(r)
+				start := s.clock.Now()
+				sw := &statusWriter{ResponseWriter: w}
+				var body *countingReader
+				if sr != nil && r.Body != nil && r.Body != http.NoBody {
+					body = &countingReader{ReadCloser: r.Body}
+					r.Body = body
+				}
+				next.ServeHTTP(sw, r)
+				if rr != nil {
+					rr.RecordRequest(r.Method, *route, sw.Status(), s.clock.Now().Sub(start))
+				}
+				if sr != nil {
+					var reqBytes int64
+					if body != nil {
+						reqBytes = body.n
+					}
+					sr.ObserveSizes(*route, reqBytes, sw.bytes)
+				}
+			})
+		})
+		return s
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
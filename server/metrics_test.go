@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+type request struct {
+	method, route string
+	status        int
+	duration      time.Duration
+}
+
+type requestRecorder struct {
+	handshakeRecorder
+	mu       sync.Mutex
+	requests []request
+}
+
+func (r *requestRecorder) RecordRequest(method, route string, status int, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, request{method, route, status, d})
+}
+
+func TestMetricsRequests(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /widgets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		clk.Advance(25 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	rec := &requestRecorder{}
+	s := New("", mux, WithClock(clk), WithMetrics(rec))
+	serve(s.server.Handler, httptest.NewRequest("GET", "/widgets/42", nil))
+	serve(s.server.Handler, httptest.NewRequest("GET", "/missing", nil))
+
+	want := []request{
+		{"GET", "GET /widgets/{id}", http.StatusAccepted, 25 * time.Millisecond},
+		{"GET", "", http.StatusNotFound, 0},
+	}
+	if len(rec.requests) != len(want) {
+		t.Fatalf("expected %d requests, got %+v", len(want), rec.requests)
+	}
+	for i := range want {
+		if rec.requests[i] != want[i] {
+			t.Errorf("request %d: expected %+v, got %+v", i, want[i], rec.requests[i])
+		}
+	}
+}
+
+type sizeRecorder struct {
+	handshakeRecorder
+	mu    sync.Mutex
+	sizes []string
+}
+
+func (r *sizeRecorder) ObserveSizes(route string, reqBytes, respBytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sizes = append(r.sizes, fmt.Sprintf("%s %d %d", route, reqBytes, respBytes))
+}
+
+func TestMetricsSizes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /upload", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write(make([]byte, 300))
+		w.Write(make([]byte, 45))
+	})
+
+	rec := &sizeRecorder{}
+	s := New("", mux, WithMetrics(rec))
+	ts := newTestServer(t, s)
+
+	// A body of unknown length is sent chunked.
+	body := struct{ io.Reader }{strings.NewReader(strings.Repeat("a", 1234))}
+	resp, err := ts.Client().Post(ts.URL+"/upload", "text/plain", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	serve(s.server.Handler, httptest.NewRequest("GET", "/missing", nil))
+
+	want := fmt.Sprint([]string{"POST /upload 1234 345", " 0 19"})
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if got := fmt.Sprint(rec.sizes); got != want {
+		t.Errorf("expected sizes %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// use appends the provided middleware to the chain wrapping the server's
+// handler, listed as name by Describe. Middleware is applied in the order it
+// was added, so the first middleware added is the first to see each request.
+func (s *Server) use(name string, mw func(http.Handler) http.Handler) {
+	s.middleware = append(s.middleware, mw)
+	s.middlewareNames = append(s.middlewareNames, name)
+}
+
+// chain wraps h in the provided middleware such that the first middleware is
+// the outermost.
+func chain(h http.Handler, mws ...func(http.Handler) http.Handler) http.Handler {
+	if len(mws) == 0 {
+		return h
+	}
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	h = captureRoute(h)
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+type routeKey struct{}
+
+// withRoute returns a copy of r with a slot in its context that will hold the
+// route pattern matched by an http.ServeMux, once the handler has returned.
+func withRoute(r *http.Request) (*http.Request, *string) {
+	if p, ok := r.Context().Value(routeKey{}).(*string); ok {
+		return r, p
+	}
+	p := new(string)
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, p)), p
+}
+
+// captureRoute wraps the innermost handler so that, after it returns, the
+// pattern set by an http.ServeMux is recorded for any middleware that asked
+// for it with withRoute. This works regardless of how many intervening
+// middlewares replaced the request.
+func captureRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if p, ok := r.Context().Value(routeKey{}).(*string); ok && r.Pattern != "" {
+			*p = r.Pattern
+		}
+	})
+}
+
+// MaxURLLength returns a middleware that rejects any request whose request URI
+// is longer than n bytes with a 414 URI Too Long.
+func MaxURLLength(n int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uri := r.RequestURI
+			if uri == "" {
+				uri = r.URL.String()
+			}
+			if len(uri) > n {
+				writeError(w, r, http.StatusRequestURITooLong, ErrURITooLong)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithMaxURLLength modifies the server to reject requests with a request URI
+// longer than n bytes.
+//
+// This complements MaxHeaderBytes, which limits the total size of the header
+// but not the length of the URL alone.
+func WithMaxURLLength(n int) Option {
+	return func(s *Server) *Server {
+		s.use("max_url_length", MaxURLLength(n))
+		return s
+	}
+}
+
+// MaxHeaderCount returns a middleware that rejects any request with more than
+// n header fields with a 431 Request Header Fields Too Large. Every value
+// counts, so a header sent three times, or once with three values merged by
+// the server, counts as three. The Host header, which is not kept in
+// r.Header, does not count.
+func MaxHeaderCount(n int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count := 0
+			for _, values := range r.Header {
+				count += max(len(values), 1)
+			}
+			if count > n {
+				writeError(w, r, http.StatusRequestHeaderFieldsTooLarge, ErrTooManyHeaders)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithMaxHeaderCount modifies the server to reject requests with more than n
+// header fields.
+//
+// This complements MaxHeaderBytes, which limits the total size of the header
+// but not how many fields it is split into, each of which costs the server an
+// allocation.
+func WithMaxHeaderCount(n int) Option {
+	return func(s *Server) *Server {
+		s.use("max_header_count", MaxHeaderCount(n))
+		return s
+	}
+}
+
+// AllowedMethods returns a middleware that rejects any request whose method is
+// not one of the provided methods with a 405 Method Not Allowed. The Allow
+// header on the response lists the permitted methods.
+func AllowedMethods(methods ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		allowed[m] = true
+	}
+	allow := strings.Join(methods, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !allowed[r.Method] {
+				w.Header().Set("Allow", allow)
+				writeError(w, r, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithAllowedMethods modifies the server to reject requests using any method
+// other than those provided. By default, all methods are allowed.
+func WithAllowedMethods(methods ...string) Option {
+	return func(s *Server) *Server {
+		s.use("allowed_methods", AllowedMethods(methods...))
+		return s
+	}
+}
+
+// MaxDrainBytes is the most DrainBody will read from an unread request body.
+// Larger bodies cause the connection to be closed instead.
+const MaxDrainBytes = 256 << 10
+
+// DrainBody is a middleware that reads and discards any of the request body
+// the handler left unread, then closes it, just before the response header is
+// written, or once the handler returns if it wrote nothing. This keeps the
+// connection in a state where it can be reused for the next request on a
+// keep-alive connection. Handlers must therefore finish reading the body
+// before they start writing the response.
+//
+// To avoid spending unbounded effort on abusive clients, at most MaxDrainBytes
+// are drained. If more of the body is left than that, the response is sent
+// with "Connection: close" instead.
+func DrainBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+		dw := &drainWriter{ResponseWriter: w, body: r.Body}
+		next.ServeHTTP(dw, r)
+		dw.drain()
+	})
+}
+
+// WithDrainBody modifies the server to drain unread request bodies after each
+// handler returns. See DrainBody for details.
+func WithDrainBody() Option {
+	return func(s *Server) *Server {
+		s.use("drain_body", DrainBody)
+		return s
+	}
+}
+
+// drainWriter drains the request body before the response header is written,
+// while it can still decide whether the connection is closed.
+type drainWriter struct {
+	http.ResponseWriter
+	body    io.ReadCloser
+	drained bool
+}
+
+// drain discards up to MaxDrainBytes of the rest of the body and closes it,
+// marking the response to close the connection if there was more. Only the
+// first call has any effect.
+func (w *drainWriter) drain() {
+	if w.drained {
+		return
+	}
+	w.drained = true
+	if n, _ := io.CopyN(io.Discard, w.body, MaxDrainBytes+1); n > MaxDrainBytes {
+		w.Header().Set("Connection", "close")
+	}
+	w.body.Close()
+}
+
+func (w *drainWriter) WriteHeader(code int) {
+	if code >= 200 {
+		w.drain()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *drainWriter) Write(p []byte) (int, error) {
+	w.drain()
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush flushes the underlying writer, if it supports flushing.
+func (w *drainWriter) Flush() {
+	w.drain()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *drainWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMaxURLLength(t *testing.T) {
+	s := New(":8080", okHandler, WithMaxURLLength(32))
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/short", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = serve(s.server.Handler, httptest.NewRequest("GET", "/"+strings.Repeat("a", 32), nil))
+	if w.Code != http.StatusRequestURITooLong {
+		t.Errorf("expected status %d, got %d", http.StatusRequestURITooLong, w.Code)
+	}
+}
+
+func TestMaxHeaderCount(t *testing.T) {
+	s := New(":8080", okHandler, WithMaxHeaderCount(4))
+
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"under", http.Header{"A": {"1"}, "B": {"1"}, "C": {"1"}}, http.StatusOK},
+		{"at", http.Header{"A": {"1"}, "B": {"1"}, "C": {"1"}, "D": {"1"}}, http.StatusOK},
+		{"above", http.Header{"A": {"1"}, "B": {"1"}, "C": {"1"}, "D": {"1"}, "E": {"1"}}, http.StatusRequestHeaderFieldsTooLarge},
+		{"multi-valued at", http.Header{"A": {"1", "2", "3"}, "B": {"1"}}, http.StatusOK},
+		{"multi-valued above", http.Header{"A": {"1", "2", "3"}, "B": {"1", "2"}}, http.StatusRequestHeaderFieldsTooLarge},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header = tt.header
+		if w := serve(s.server.Handler, r); w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	s := New(":8080", okHandler, WithAllowedMethods(http.MethodGet, http.MethodPost))
+
+	w := serve(s.server.Handler, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = serve(s.server.Handler, httptest.NewRequest(http.MethodTrace, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if got, want := w.Header().Get("Allow"), "GET, POST"; got != want {
+		t.Errorf("expected Allow header %q, got %q", want, got)
+	}
+}
+
+func TestDrainBody(t *testing.T) {
+	s := New(":8080", okHandler, WithDrainBody())
+	ts := newTestServer(t, s)
+	c := ts.Client()
+
+	var reused []bool
+	for i := 0; i < 2; i++ {
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) { reused = append(reused, info.Reused) },
+		}
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(strings.Repeat("a", 1024)))
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if len(reused) != 2 || !reused[1] {
+		t.Errorf("expected the second request to reuse the connection, got %v", reused)
+	}
+}
+
+func TestDrainBodyTooLarge(t *testing.T) {
+	s := New(":8080", okHandler, WithDrainBody())
+	ts := newTestServer(t, s)
+
+	// Hide the length of the body so it is sent chunked.
+	body := io.MultiReader(strings.NewReader(strings.Repeat("a", MaxDrainBytes+1)))
+	resp, err := ts.Client().Post(ts.URL, "text/plain", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !resp.Close {
+		t.Error("expected the connection to be closed")
+	}
+}
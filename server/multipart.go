@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// DefaultMaxMultipartMemory is the number of bytes of a multipart form's files
+// that ParseMultipart keeps in memory when the server does not set a limit with
+// WithMaxMultipartMemory. It matches the default used by http.Request.FormFile.
+const DefaultMaxMultipartMemory = 32 << 20
+
+type multipartKey struct{}
+
+// multipartState carries the server's multipart memory limit through a
+// request's context and collects the forms parsed with it so their temporary
+// files can be removed.
+type multipartState struct {
+	maxMemory int64
+
+	mu    sync.Mutex
+	forms []*multipart.Form
+}
+
+// WithMaxMultipartMemory modifies the server so ParseMultipart keeps at most n
+// bytes of a multipart form's files in memory, storing the rest in temporary
+// files on disk. Temporary files created by ParseMultipart are removed once the
+// handler returns.
+func WithMaxMultipartMemory(n int64) Option {
+	return func(s *Server) *Server {
+		s.use("max_multipart_memory", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				st := &multipartState{maxMemory: n}
+				defer st.removeAll()
+				ctx := context.WithValue(r.Context(), multipartKey{}, st)
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+		return s
+	}
+}
+
+// ParseMultipart parses r's body as a multipart form with r.ParseMultipartForm,
+// using the memory limit set with WithMaxMultipartMemory, or
+// DefaultMaxMultipartMemory if there is none. Handlers should call it instead
+// of choosing a limit of their own, so every route shares the server's.
+//
+// On a server configured with WithMaxMultipartMemory, the form's temporary
+// files are removed when the handler returns, even if r is a copy made by
+// middleware, which net/http does not clean up after.
+func ParseMultipart(r *http.Request) error {
+	st, _ := r.Context().Value(multipartKey{}).(*multipartState)
+	maxMemory := int64(DefaultMaxMultipartMemory)
+	if st != nil {
+		maxMemory = st.maxMemory
+	}
+	err := r.ParseMultipartForm(maxMemory)
+	if st != nil && r.MultipartForm != nil {
+		st.add(r.MultipartForm)
+	}
+	return err
+}
+
+func (st *multipartState) add(f *multipart.Form) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !slices.Contains(st.forms, f) {
+		st.forms = append(st.forms, f)
+	}
+}
+
+// removeAll removes the temporary files of every form parsed for the request.
+func (st *multipartState) removeAll() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, f := range st.forms {
+		f.RemoveAll()
+	}
+}
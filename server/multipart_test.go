@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func multipartRequest(t *testing.T, size int) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("upload", "data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(strings.Repeat("a", size)))
+	mw.Close()
+	r := httptest.NewRequest("POST", "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestMaxMultipartMemory(t *testing.T) {
+	const limit = 1024
+	var tmpfile string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Parse a copy of the request, as middleware might, so net/http
+		// itself would not clean up after it.
+		r = r.WithContext(r.Context())
+		if err := ParseMultipart(r); err != nil {
+			t.Fatal(err)
+		}
+		f, err := r.MultipartForm.File["upload"][0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if osf, ok := f.(*os.File); ok {
+			tmpfile = osf.Name()
+		}
+	})
+	s := New("", h, WithMaxMultipartMemory(limit))
+
+	t.Run("under", func(t *testing.T) {
+		tmpfile = ""
+		serve(s.server.Handler, multipartRequest(t, limit/2))
+		if tmpfile != "" {
+			t.Errorf("file under the limit was stored on disk at %s", tmpfile)
+		}
+	})
+
+	t.Run("over", func(t *testing.T) {
+		tmpfile = ""
+		serve(s.server.Handler, multipartRequest(t, 4*limit))
+		if tmpfile == "" {
+			t.Fatal("file over the limit was kept in memory")
+		}
+		if _, err := os.Stat(tmpfile); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("temporary file %s was not removed: %v", tmpfile, err)
+		}
+	})
+}
+
+func TestParseMultipartDefault(t *testing.T) {
+	var inMemory bool
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := ParseMultipart(r); err != nil {
+			t.Fatal(err)
+		}
+		defer r.MultipartForm.RemoveAll()
+		f, err := r.MultipartForm.File["upload"][0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		_, onDisk := f.(*os.File)
+		inMemory = !onDisk
+	})
+
+	serve(h, multipartRequest(t, 64<<10))
+	if !inMemory {
+		t.Error("file under DefaultMaxMultipartMemory was stored on disk")
+	}
+}
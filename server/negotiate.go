@@ -0,0 +1,58 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// qvalue is a single entry from a header such as Accept or Accept-Encoding,
+// along with its quality value.
+type qvalue struct {
+	value string
+	q     float64
+}
+
+// parseQList parses a comma-separated list of values with optional quality
+// parameters, as used by the Accept family of headers. The result is sorted by
+// descending quality, preserving the original order for equal qualities.
+// Entries with invalid quality values are skipped.
+func parseQList(header string) []qvalue {
+	var list []qvalue
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		q, ok := 1.0, true
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(strings.ToLower(k)) != "q" {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || f < 0 || f > 1 {
+				ok = false
+				break
+			}
+			q = f
+		}
+		if ok {
+			list = append(list, qvalue{value: value, q: q})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+	return list
+}
+
+// matchMediaType reports whether the media range, which may contain wildcards,
+// matches the concrete media type.
+func matchMediaType(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
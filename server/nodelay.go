@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"net"
+)
+
+// WithTCPNoDelay modifies the server to set TCP_NODELAY to noDelay on every
+// connection it accepts. Since Go already sets it on accepted connections,
+// this matters mostly for servers that write many small responses over
+// long-lived connections and would rather the kernel batch them into fewer
+// packets: passing false turns Nagle's algorithm back on for them.
+//
+// The option looks through TLS for the TCP connection beneath. Connections
+// that are not TCP, such as those on a Unix socket, are unaffected.
+func WithTCPNoDelay(noDelay bool) Option {
+	return func(s *Server) *Server {
+		s.onConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			if tc := tcpConn(c); tc != nil {
+				tc.SetNoDelay(noDelay)
+			}
+			return ctx
+		})
+		return s
+	}
+}
+
+// tcpConn unwraps c through any number of layers exposing NetConn, such as
+// *tls.Conn, until it finds a *net.TCPConn. It returns nil if c is not backed
+// by TCP.
+func tcpConn(c net.Conn) *net.TCPConn {
+	for {
+		if tc, ok := c.(*net.TCPConn); ok {
+			return tc
+		}
+		nc, ok := c.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		c = nc.NetConn()
+	}
+}
@@ -0,0 +1,51 @@
+//go:build linux || darwin
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+)
+
+// noDelay reports whether TCP_NODELAY is set on conn.
+func noDelay(t *testing.T, conn net.Conn) bool {
+	t.Helper()
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	var serr error
+	raw.Control(func(fd uintptr) {
+		v, serr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+	})
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	return v != 0
+}
+
+func TestTCPNoDelay(t *testing.T) {
+	for _, want := range []bool{false, true} {
+		got := make(chan bool, 1)
+		s := New("", okHandler, WithTCPNoDelay(want))
+		s.onConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			got <- noDelay(t, c)
+			return ctx
+		})
+		ts := newTestServer(t, s)
+
+		resp, err := http.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if g := <-got; g != want {
+			t.Errorf("expected TCP_NODELAY %t, got %t", want, g)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithNotFoundFallback modifies the server to answer any request whose handler
+// returns without writing a response, neither writing a header nor any of the
+// body, with 404 Not Found, written by the error responder with the cause
+// ErrNotFound. Without it, such a request gets an empty 200 OK, which hides a
+// handler that forgot to respond or a route that matched by mistake.
+//
+// Handlers that hijack the connection must do so with Hijack, which the
+// fallback recognizes, rather than directly through http.ResponseController.
+func WithNotFoundFallback() Option {
+	return func(s *Server) *Server {
+		s.use("not_found_fallback", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hijacked := new(bool)
+				r = r.WithContext(context.WithValue(r.Context(), hijackedKey{}, hijacked))
+				sw := &statusWriter{ResponseWriter: w}
+				next.ServeHTTP(sw, r)
+				if sw.status == 0 && !*hijacked {
+					writeError(w, r, http.StatusNotFound, ErrNotFound)
+				}
+			})
+		})
+		return s
+	}
+}
+
+type hijackedKey struct{}
+
+// markHijacked records that the connection of the request with ctx has been
+// hijacked, for WithNotFoundFallback.
+func markHijacked(ctx context.Context) {
+	if hijacked, ok := ctx.Value(hijackedKey{}).(*bool); ok {
+		*hijacked = true
+	}
+}
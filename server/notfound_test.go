@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotFoundFallback(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/flushed", func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+	})
+	mux.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/body", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("widgets"))
+	})
+
+	s := New("", mux, WithErrorResponder(jsonErrors), WithNotFoundFallback())
+	for _, tt := range []struct {
+		path   string
+		status int
+	}{
+		{"/flushed", http.StatusOK},
+		{"/created", http.StatusCreated},
+		{"/body", http.StatusOK},
+	} {
+		if w := serve(s.server.Handler, httptest.NewRequest("GET", tt.path, nil)); w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+	}
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/empty", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	var env errorEnvelope
+	if err := json.NewDecoder(w.Body).Decode(&env); err != nil {
+		t.Fatalf("expected a JSON envelope: %v", err)
+	}
+	if env.Status != http.StatusNotFound || env.Error != ErrNotFound.Error() {
+		t.Errorf("expected envelope for %v, got %+v", ErrNotFound, env)
+	}
+}
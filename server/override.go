@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MethodOverrideHeader is the header MethodOverride reads the real method of a
+// request from.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverride is a middleware that serves a POST request carrying an
+// X-HTTP-Method-Override header of PUT, PATCH or DELETE as if it had been sent
+// with that method, for clients behind proxies that block those methods. Any
+// other method in the header, and the header on requests that are not POST,
+// is ignored, so the header can never turn a request into a GET or HEAD.
+//
+// It must run before routing, and before anything that checks the method such
+// as WithAllowedMethods, so that they see the real method.
+func MethodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			switch m := strings.ToUpper(r.Header.Get(MethodOverrideHeader)); m {
+			case http.MethodPut, http.MethodPatch, http.MethodDelete:
+				r = r.Clone(r.Context())
+				r.Method = m
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithMethodOverride modifies the server to honor the X-HTTP-Method-Override
+// header on POST requests. See MethodOverride.
+func WithMethodOverride() Option {
+	return func(s *Server) *Server {
+		s.use("method_override", MethodOverride)
+		return s
+	}
+}
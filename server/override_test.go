@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("deleted " + r.PathValue("id")))
+	})
+	s := New(":8080", mux, WithMethodOverride())
+
+	for _, tt := range []struct {
+		method, override string
+		want             int
+	}{
+		{"POST", "DELETE", http.StatusOK},
+		{"POST", "delete", http.StatusOK},
+		{"POST", "", http.StatusMethodNotAllowed},
+		{"GET", "DELETE", http.StatusMethodNotAllowed},
+		{"POST", "GET", http.StatusMethodNotAllowed},
+	} {
+		r := httptest.NewRequest(tt.method, "/items/1", nil)
+		if tt.override != "" {
+			r.Header.Set(MethodOverrideHeader, tt.override)
+		}
+		if w := serve(s.server.Handler, r); w.Code != tt.want {
+			t.Errorf("%s overridden to %q: expected %d, got %d", tt.method, tt.override, tt.want, w.Code)
+		}
+	}
+}
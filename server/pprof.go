@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+	rpprof "runtime/pprof"
+	"strings"
+)
+
+// PprofPrefix is the path under which PprofHandler serves its endpoints by
+// default.
+const PprofPrefix = "/debug/pprof/"
+
+// PprofOptions configures PprofHandler.
+type PprofOptions struct {
+	// Prefix is the path under which the endpoints are served. It defaults to
+	// PprofPrefix.
+	Prefix string
+
+	// Authorize, if set, is called for every request, which is rejected with
+	// 403 Forbidden and ErrForbidden unless it returns true.
+	Authorize func(r *http.Request) bool
+}
+
+// PprofHandler returns a handler serving net/http/pprof's endpoints under
+// opts.Prefix:
+//
+//	/debug/pprof/             an index of the available profiles
+//	/debug/pprof/cmdline      the program's command line
+//	/debug/pprof/profile      a CPU profile, for ?seconds=N (default 30)
+//	/debug/pprof/trace        an execution trace, for ?seconds=N (default 1)
+//	/debug/pprof/symbol       the names of program counters
+//	/debug/pprof/{name}       a named profile, such as heap or goroutine
+//
+// Profiles can reveal a great deal about a program, so the handler is meant to
+// be mounted on a separate Server listening on an internal admin address,
+// ideally with opts.Authorize set, and never on the public one. Note that
+// importing net/http/pprof also registers these endpoints on
+// http.DefaultServeMux, so DefaultServeMux must not be served publicly.
+//
+// Collecting a CPU profile or trace takes as long as requested, so the write
+// deadline of those requests is extended by that long beyond the serving
+// Server's write timeout.
+func PprofHandler(opts PprofOptions) http.Handler {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = PprofPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.Authorize != nil && !opts.Authorize(r) {
+			writeError(w, r, http.StatusForbidden, ErrForbidden)
+			return
+		}
+		name, ok := strings.CutPrefix(r.URL.Path, prefix)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrNotFound)
+			return
+		}
+
+		switch name {
+		case "":
+			pprof.Index(w, r)
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		default:
+			if rpprof.Lookup(name) == nil {
+				writeError(w, r, http.StatusNotFound, ErrNotFound)
+				return
+			}
+			pprof.Handler(name).ServeHTTP(w, r)
+		}
+	})
+}
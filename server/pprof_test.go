@@ -0,0 +1,70 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPprofHandler(t *testing.T) {
+	h := PprofHandler(PprofOptions{})
+
+	w := serve(h, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	for _, name := range []string{"goroutine", "heap", "profile"} {
+		if !strings.Contains(w.Body.String(), ">"+name+"<") {
+			t.Errorf("expected the index to list %s", name)
+		}
+	}
+
+	w = serve(h, httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine profile:") {
+		t.Errorf("expected a goroutine profile, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = serve(h, httptest.NewRequest("GET", "/debug/pprof/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown profile, got %d", w.Code)
+	}
+}
+
+func TestPprofHandlerAuthorize(t *testing.T) {
+	s := New(":8080", PprofHandler(PprofOptions{
+		Prefix:    "/admin/pprof",
+		Authorize: func(r *http.Request) bool { return r.Header.Get("X-Admin") == "yes" },
+	}), WithErrorResponder(jsonErrors))
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/admin/pprof/", nil))
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), ErrForbidden.Error()) {
+		t.Errorf("expected 403 with ErrForbidden, got %d %q", w.Code, w.Body.String())
+	}
+
+	r := httptest.NewRequest("GET", "/admin/pprof/", nil)
+	r.Header.Set("X-Admin", "yes")
+	if w := serve(s.server.Handler, r); w.Code != http.StatusOK {
+		t.Errorf("expected 200 when authorized, got %d", w.Code)
+	}
+}
+
+func TestPprofHandlerExtendsWriteDeadline(t *testing.T) {
+	s := New(":8080", PprofHandler(PprofOptions{}), WithWriteTimeout(100*time.Millisecond))
+	ts := httptest.NewUnstartedServer(s.server.Handler)
+	ts.Config.WriteTimeout = s.server.WriteTimeout
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/debug/pprof/trace?seconds=0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Errorf("expected a trace, got %d with %d bytes (err %v)", resp.StatusCode, len(body), err)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/haleyrc/http/internal/propagation"
+)
+
+// WithPropagation modifies the server to collect the values of headers from
+// every incoming request into the request context's propagation bag, from
+// which a client configured with the client package's WithPropagation sends
+// them on the outbound requests made while handling it. Headers absent from
+// the request are left out of the bag, except that X-Request-Id, if listed,
+// falls back to the ID assigned by WithRequestID when that option was added
+// before this one.
+func WithPropagation(headers ...string) Option {
+	return func(s *Server) *Server {
+		s.use("propagation", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bag := make(propagation.Bag, len(headers))
+				for _, h := range headers {
+					h = http.CanonicalHeaderKey(h)
+					v := r.Header.Get(h)
+					if v == "" && h == RequestIDHeader {
+						v = RequestIDFromContext(r.Context())
+					}
+					if v != "" {
+						bag[h] = v
+					}
+				}
+				if len(bag) > 0 {
+					r = r.WithContext(propagation.NewContext(r.Context(), bag))
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haleyrc/http/client"
+)
+
+func TestPropagationToClient(t *testing.T) {
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Tenant")+" "+r.Header.Get(RequestIDHeader))
+	}))
+	defer downstream.Close()
+
+	c := client.New(client.WithPropagation("X-Tenant", RequestIDHeader))
+	s := New(":8080", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", downstream.URL, nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(w, resp.Body)
+	}), WithRequestID(), WithPropagation("X-Tenant", RequestIDHeader))
+	ts := newTestServer(t, s)
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	id := resp.Header.Get(RequestIDHeader)
+	if id == "" {
+		t.Fatal("expected a generated request ID")
+	}
+	if want := "acme " + id; string(body) != want {
+		t.Errorf("expected the downstream to see %q, got %q", want, body)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/haleyrc/http/internal/ratelimit"
+)
+
+type (
+	readBucketKey  struct{}
+	writeBucketKey struct{}
+)
+
+// WithReadRateLimit modifies the server to read request bodies no faster than
+// bytesPerSec bytes per second on each connection, so a client uploading
+// slowly but steadily cannot take more than its share of bandwidth. Every
+// request on a connection, including concurrent HTTP/2 streams, draws from
+// the same token bucket, which holds a tenth of a second's worth of bytes.
+//
+// A read waiting for the limit fails with the request context's error once the
+// request is canceled. The limit makes uploads take longer, so the read
+// timeout must allow for it, for example with WithUploadReadTimeout. It is
+// also useful in tests, to exercise a client's timeouts against a
+// deliberately slow server. A bytesPerSec that is not positive leaves reads
+// unlimited.
+func WithReadRateLimit(bytesPerSec int64) Option {
+	return func(s *Server) *Server {
+		if bytesPerSec <= 0 {
+			return s
+		}
+		s.onConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, readBucketKey{}, ratelimit.NewBucket(bytesPerSec, s.clock))
+		})
+		s.use("read_rate_limit", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if b, ok := r.Context().Value(readBucketKey{}).(*ratelimit.Bucket); ok && r.Body != nil && r.Body != http.NoBody {
+					r.Body = rateLimitedBody{ratelimit.NewReader(r.Context(), r.Body, b), r.Body}
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
+
+// WithWriteRateLimit modifies the server to write responses no faster than
+// bytesPerSec bytes per second on each connection, sharing a token bucket
+// between requests as WithReadRateLimit does. A write waiting for the limit
+// fails with the request context's error once the request is canceled, and
+// the write timeout must allow for the time the limit adds to large
+// responses. A bytesPerSec that is not positive leaves writes unlimited.
+func WithWriteRateLimit(bytesPerSec int64) Option {
+	return func(s *Server) *Server {
+		if bytesPerSec <= 0 {
+			return s
+		}
+		s.onConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, writeBucketKey{}, ratelimit.NewBucket(bytesPerSec, s.clock))
+		})
+		s.use("write_rate_limit", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if b, ok := r.Context().Value(writeBucketKey{}).(*ratelimit.Bucket); ok {
+					w = &rateLimitedWriter{ResponseWriter: w, ctx: r.Context(), bucket: b}
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		return s
+	}
+}
+
+// rateLimitedBody is a request body read through a rate-limited reader.
+type rateLimitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// rateLimitedWriter paces the body written through it. It deliberately hides
+// the io.ReaderFrom of the underlying writer, which would bypass the limit.
+type rateLimitedWriter struct {
+	http.ResponseWriter
+	ctx    context.Context
+	bucket *ratelimit.Bucket
+}
+
+func (w *rateLimitedWriter) Write(p []byte) (int, error) {
+	return ratelimit.Write(w.ctx, w.ResponseWriter, p, w.bucket)
+}
+
+// Flush flushes the underlying writer, if it supports flushing.
+func (w *rateLimitedWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (w *rateLimitedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
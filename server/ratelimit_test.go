@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rateLimitSize and rateLimit make the throttled transfers take about 0.4s
+// once the initial burst of a tenth of a second's worth is spent.
+const (
+	rateLimitSize = 20 << 10
+	rateLimit     = 40 << 10
+)
+
+func expectDuration(t *testing.T, got time.Duration) {
+	t.Helper()
+	const want = 400 * time.Millisecond
+	if got < want*3/4 || got > want*3 {
+		t.Errorf("expected the transfer to take about %s, took %s", want, got)
+	}
+}
+
+func TestReadRateLimit(t *testing.T) {
+	var elapsed time.Duration
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		n, _ := io.Copy(io.Discard, r.Body)
+		elapsed = time.Since(start)
+		if n != rateLimitSize {
+			t.Errorf("expected to read %d bytes, read %d", rateLimitSize, n)
+		}
+	})
+	s := New(":8080", h, WithReadRateLimit(rateLimit))
+	ts := newTestServer(t, s)
+
+	resp, err := ts.Client().Post(ts.URL, "text/plain", bytes.NewReader(make([]byte, rateLimitSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	expectDuration(t, elapsed)
+}
+
+func TestWriteRateLimit(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, rateLimitSize))
+	})
+	s := New(":8080", h, WithWriteRateLimit(rateLimit))
+	ts := newTestServer(t, s)
+
+	start := time.Now()
+	resp, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if n != rateLimitSize {
+		t.Errorf("expected %d bytes, got %d", rateLimitSize, n)
+	}
+	expectDuration(t, time.Since(start))
+}
+
+func TestWriteRateLimitCanceled(t *testing.T) {
+	errc := make(chan error, 1)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NewResponseController(w).Flush()
+		_, err := w.Write(make([]byte, 1<<20))
+		errc <- err
+	})
+	s := New(":8080", h, WithWriteRateLimit(1024))
+	ts := newTestServer(t, s)
+
+	resp, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), "canceled") {
+			t.Errorf("expected the write to fail with the request's cancellation, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the write to stop once the client went away")
+	}
+}
+
+func TestRateLimitNotPositive(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	s := New(":8080", h, WithReadRateLimit(0), WithWriteRateLimit(-1))
+	ts := newTestServer(t, s)
+
+	c := ts.Client()
+	c.Timeout = 2 * time.Second
+	resp, err := c.Post(ts.URL, "text/plain", strings.NewReader("unlimited"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(got) != "unlimited" {
+		t.Errorf("expected the body to be echoed, got %q", got)
+	}
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connTracker counts the connections that are actively serving a request, as
+// reported by the http.Server's ConnState hook.
+type connTracker struct {
+	mu     sync.Mutex
+	states map[net.Conn]http.ConnState
+	active int64
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{states: make(map[net.Conn]http.ConnState)}
+}
+
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev := t.states[c]
+	switch {
+	case prev != http.StateActive && state == http.StateActive:
+		t.active++
+	case prev == http.StateActive && state != http.StateActive:
+		t.active--
+	}
+
+	if state == http.StateClosed || state == http.StateHijacked {
+		delete(t.states, c)
+	} else {
+		t.states[c] = state
+	}
+}
+
+// Active returns the number of connections currently serving a request.
+func (t *connTracker) Active() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active
+}
+
+// readiness holds the state reported by the readiness endpoint.
+type readiness struct {
+	draining atomic.Bool
+
+	// shuttingDown is set once the drain delay is over and the graceful
+	// shutdown has begun.
+	shuttingDown atomic.Bool
+
+	// lameDuck is set, along with draining, once the server has entered lame
+	// duck mode.
+	lameDuck atomic.Bool
+}
+
+type readinessResponse struct {
+	Status   string `json:"status"`
+	InFlight int64  `json:"in_flight"`
+}
+
+// WithReadiness modifies the server to answer readiness probes at path, such
+// as "/readyz", before any other handler sees the request.
+//
+// The probe succeeds with a 200 until the server begins shutting down. From
+// then on, including during any drain delay, it fails with a 503, and the JSON
+// body includes the number of other connections still serving a request so
+// operators can watch the drain progress:
+//
+//	{"status":"draining","in_flight":3}
+//
+// While the server is in lame duck mode, entered with WithLameDuckSignal, the
+// probe fails in the same way with a "lame_duck" status.
+func WithReadiness(path string) Option {
+	return func(s *Server) *Server {
+		s.use("readiness("+path+")", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != path {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				// The probe's own connection is active while it is served.
+				resp := readinessResponse{Status: "ok", InFlight: max(s.conns.Active()-1, 0)}
+				status := http.StatusOK
+				switch {
+				case s.ready.lameDuck.Load() && !s.ready.shuttingDown.Load():
+					resp.Status = "lame_duck"
+					status = http.StatusServiceUnavailable
+				case s.ready.draining.Load():
+					resp.Status = "draining"
+					status = http.StatusServiceUnavailable
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Cache-Control", "no-store")
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(resp)
+			})
+		})
+		return s
+	}
+}
+
+// WithDrainDelay modifies the server to wait for d after a shutdown is
+// triggered before it stops accepting new connections. During the delay the
+// server continues to serve normally, but the readiness endpoint fails, giving
+// load balancers time to stop routing new traffic to it. A second signal ends
+// the delay early.
+func WithDrainDelay(d time.Duration) Option {
+	return func(s *Server) *Server {
+		s.drainDelay = d
+		return s
+	}
+}
+
+// DrainAckTimeout bounds how long the server waits for the acknowledgement set
+// with WithDrainAck when no drain delay is set.
+const DrainAckTimeout = 30 * time.Second
+
+// WithDrainAck modifies the server to wait for wait to return after a shutdown
+// is triggered, rather than for a fixed drain delay, before it stops accepting
+// new connections. wait should block until something outside the server, such
+// as a service mesh sidecar signalling through a local endpoint or a file,
+// confirms that traffic has stopped being routed to it. As with
+// WithDrainDelay, the server serves normally while waiting, but the readiness
+// endpoint fails.
+//
+// The wait is bounded by the drain delay, if one is set, or DrainAckTimeout
+// otherwise, and a second signal ends it early. The context passed to wait is
+// cancelled once the server stops waiting. If wait returns an error, the error
+// is reported to the error writer and the shutdown proceeds.
+func WithDrainAck(wait func(ctx context.Context) error) Option {
+	return func(s *Server) *Server {
+		s.drainAck = wait
+		return s
+	}
+}
+
+// WithLameDuckSignal modifies the server to enter lame duck mode when it
+// receives sig, such as syscall.SIGUSR1, rather than shutting down. In lame
+// duck mode the server keeps serving, but the readiness endpoint fails with a
+// "lame_duck" status and keep-alives are disabled, so the instance is taken out
+// of rotation and its clients move elsewhere while it stays up for
+// inspection.
+//
+// Receiving sig again, or any of the usual shutdown signals, then shuts the
+// server down. The drain delay is skipped, since the server has already been
+// out of rotation.
+func WithLameDuckSignal(sig os.Signal) Option {
+	return func(s *Server) *Server {
+		s.lameDuckSignal = sig
+		return s
+	}
+}
+
+// enterLameDuck puts the server into lame duck mode if sig is the lame duck
+// signal and it is not already in it, and reports whether it did.
+func (s *Server) enterLameDuck(sig os.Signal) bool {
+	if s.lameDuckSignal == nil || sig != s.lameDuckSignal || s.ready.lameDuck.Load() {
+		return false
+	}
+	fmt.Fprintln(s.out, "entering lame duck mode")
+	s.ready.lameDuck.Store(true)
+	s.ready.draining.Store(true)
+	s.server.SetKeepAlivesEnabled(false)
+	return true
+}
+
+// drain marks the server as draining and waits for the drain acknowledgement
+// or the drain delay, if any.
+func (s *Server) drain() {
+	s.ready.draining.Store(true)
+	if s.drainAck != nil {
+		s.waitDrainAck()
+		return
+	}
+	if s.drainDelay <= 0 {
+		return
+	}
+	select {
+	case <-s.clock.After(s.drainDelay):
+	case <-s.signals:
+	}
+}
+
+// waitDrainAck calls the drain acknowledgement function and waits for it to
+// return, for its bound to pass or for a second signal.
+func (s *Server) waitDrainAck() {
+	timeout := s.drainDelay
+	if timeout <= 0 {
+		timeout = DrainAckTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.drainAck(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			fmt.Fprintf(s.err, "drain acknowledgement failed: %v\n", err)
+		}
+	case <-s.clock.After(timeout):
+		fmt.Fprintf(s.err, "drain acknowledgement timed out after %s\n", timeout)
+	case <-s.signals:
+	}
+}
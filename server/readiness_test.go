@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+)
+
+func probe(t *testing.T, addr string) (int, readinessResponse) {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body readinessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestReadinessDuringDrain(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	clk := clock.NewFake(time.Now())
+	s := New("", h,
+		WithReadiness("/readyz"),
+		WithDrainDelay(10*time.Second),
+		WithClock(clk),
+		WithOutputWriter(io.Discard),
+	)
+	addr, errc := start(t, s)
+
+	if status, body := probe(t, addr); status != http.StatusOK || body.Status != "ok" {
+		t.Errorf("expected ready, got %d %+v", status, body)
+	}
+
+	go http.Get("http://" + addr + "/slow")
+	<-entered
+
+	s.signals <- syscall.SIGTERM
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	status, body := probe(t, addr)
+	if status != http.StatusServiceUnavailable || body.Status != "draining" || body.InFlight != 1 {
+		t.Errorf("expected draining with 1 in flight, got %d %+v", status, body)
+	}
+
+	close(release)
+	clk.Advance(10 * time.Second)
+	if err := <-errc; err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestLameDuck(t *testing.T) {
+	s := New("", okHandler,
+		WithReadiness("/readyz"),
+		WithLameDuckSignal(syscall.SIGHUP),
+		WithDrainDelay(time.Hour),
+		WithOutputWriter(io.Discard),
+	)
+	addr, errc := start(t, s)
+
+	s.signals <- syscall.SIGHUP
+	for !s.ready.lameDuck.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if status, body := probe(t, addr); status != http.StatusServiceUnavailable || body.Status != "lame_duck" {
+		t.Errorf("expected lame duck, got %d %+v", status, body)
+	}
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the server to keep serving, got status %d", resp.StatusCode)
+	}
+	if !resp.Close {
+		t.Error("expected keep-alives to be disabled")
+	}
+
+	// A second lame duck signal shuts down without waiting out the drain
+	// delay.
+	s.signals <- syscall.SIGHUP
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the server to shut down")
+	}
+}
+
+func TestDrainAck(t *testing.T) {
+	waiting, ack := make(chan struct{}), make(chan struct{})
+	s := New("", okHandler,
+		WithReadiness("/readyz"),
+		WithDrainAck(func(ctx context.Context) error {
+			close(waiting)
+			<-ack
+			return nil
+		}),
+		WithOutputWriter(io.Discard),
+	)
+	addr, errc := start(t, s)
+
+	s.signals <- syscall.SIGTERM
+	<-waiting
+
+	if status, body := probe(t, addr); status != http.StatusServiceUnavailable || body.Status != "draining" {
+		t.Errorf("expected draining, got %d %+v", status, body)
+	}
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the server to keep serving, got status %d", resp.StatusCode)
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("server shut down before the drain was acknowledged: %v", err)
+	default:
+	}
+
+	close(ack)
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the server to shut down")
+	}
+}
+
+func TestDrainAckTimeout(t *testing.T) {
+	var errs strings.Builder
+	cancelled := make(chan struct{})
+	clk := clock.NewFake(time.Now())
+	s := New("", okHandler,
+		WithDrainAck(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		}),
+		WithDrainDelay(10*time.Second),
+		WithClock(clk),
+		WithOutputWriter(io.Discard),
+		WithErrorWriter(&errs),
+	)
+	_, errc := start(t, s)
+
+	s.signals <- syscall.SIGTERM
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(10 * time.Second)
+
+	if err := <-errc; err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	<-cancelled
+	if !strings.Contains(errs.String(), "drain acknowledgement timed out after 10s") {
+		t.Errorf("expected a timeout to be reported, got %q", errs.String())
+	}
+}
+
+func TestDrainAckSecondSignal(t *testing.T) {
+	waiting := make(chan struct{})
+	s := New("", okHandler,
+		WithDrainAck(func(ctx context.Context) error {
+			close(waiting)
+			<-ctx.Done()
+			return nil
+		}),
+		WithOutputWriter(io.Discard),
+	)
+	_, errc := start(t, s)
+
+	s.signals <- syscall.SIGTERM
+	<-waiting
+	s.signals <- syscall.SIGTERM
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("expected nil error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a second signal to end the wait")
+	}
+}
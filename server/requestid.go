@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header used to carry request IDs, both on incoming
+// requests and on responses.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored in ctx by RequestID, or
+// the empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID is a middleware that assigns every request an ID, stores it in the
+// request context, and echoes it in the response's X-Request-Id header. If the
+// incoming request already carries an X-Request-Id header, that value is used
+// instead of generating a new one.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// WithRequestID modifies the server to assign an ID to every request. See
+// RequestID for details.
+func WithRequestID() Option {
+	return func(s *Server) *Server {
+		s.use("request_id", RequestID)
+		return s
+	}
+}
+
+// requestID returns the ID for r, preferring the one assigned by RequestID and
+// falling back to the incoming header.
+func requestID(r *http.Request) string {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(RequestIDHeader)
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	w := serve(h, httptest.NewRequest("GET", "/", nil))
+	if got == "" {
+		t.Fatal("expected a generated request ID")
+	}
+	if hdr := w.Header().Get(RequestIDHeader); hdr != got {
+		t.Errorf("expected response header %q, got %q", got, hdr)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestIDHeader, "abc123")
+	serve(h, r)
+	if got != "abc123" {
+		t.Errorf("expected request ID %q, got %q", "abc123", got)
+	}
+}
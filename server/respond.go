@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Encoder writes v to w in a particular format.
+type Encoder func(w io.Writer, v any) error
+
+var (
+	encodersMu sync.RWMutex
+
+	// encoders holds the media types Respond can produce, in order of
+	// preference when the client has no preference of its own.
+	encoders = []mediaEncoder{
+		{"application/json", func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }},
+		{"application/xml", func(w io.Writer, v any) error { return xml.NewEncoder(w).Encode(v) }},
+		{"text/xml", func(w io.Writer, v any) error { return xml.NewEncoder(w).Encode(v) }},
+	}
+)
+
+type mediaEncoder struct {
+	mediaType string
+	encode    Encoder
+}
+
+// RegisterEncoder makes enc available to Respond for the given media type,
+// replacing any existing encoder for that type. JSON and XML encoders are
+// registered by default.
+func RegisterEncoder(mediaType string, enc Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i, e := range encoders {
+		if e.mediaType == mediaType {
+			encoders[i].encode = enc
+			return
+		}
+	}
+	encoders = append(encoders, mediaEncoder{mediaType, enc})
+}
+
+// negotiate returns the registered encoder best matching the Accept header of
+// r, falling back to JSON if the client has no preference or accepts nothing
+// we can produce.
+func negotiate(r *http.Request) mediaEncoder {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	for _, accept := range parseQList(r.Header.Get("Accept")) {
+		if accept.q == 0 {
+			continue
+		}
+		for _, e := range encoders {
+			if matchMediaType(accept.value, e.mediaType) {
+				return e
+			}
+		}
+	}
+	return encoders[0]
+}
+
+// Respond writes v to w with the provided status code, encoded in the format
+// requested by the Accept header of r. JSON is used unless the client prefers
+// another registered format, such as XML.
+//
+// The response is encoded in full before anything is written, so if encoding
+// fails a 500 Internal Server Error is written instead and the encoding error
+// is returned.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v any) error {
+	e := negotiate(r)
+
+	var buf bytes.Buffer
+	if err := e.encode(&buf, v); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return err
+	}
+
+	w.Header().Set("Content-Type", e.mediaType+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type widget struct {
+	ID   int    `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+func TestRespond(t *testing.T) {
+	tests := []struct {
+		accept string
+		ct     string
+		body   string
+	}{
+		{"", "application/json; charset=utf-8", `{"id":1,"name":"gear"}` + "\n"},
+		{"*/*", "application/json; charset=utf-8", `{"id":1,"name":"gear"}` + "\n"},
+		{"application/xml", "application/xml; charset=utf-8", `<widget><id>1</id><name>gear</name></widget>`},
+		{"application/json;q=0.5, application/xml", "application/xml; charset=utf-8", `<widget><id>1</id><name>gear</name></widget>`},
+		{"text/*", "text/xml; charset=utf-8", `<widget><id>1</id><name>gear</name></widget>`},
+		{"image/png", "application/json; charset=utf-8", `{"id":1,"name":"gear"}` + "\n"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		if err := Respond(w, r, http.StatusCreated, widget{1, "gear"}); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusCreated {
+			t.Errorf("%q: expected status %d, got %d", tt.accept, http.StatusCreated, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != tt.ct {
+			t.Errorf("%q: expected content type %q, got %q", tt.accept, tt.ct, got)
+		}
+		if got := w.Body.String(); got != tt.body {
+			t.Errorf("%q: expected body %q, got %q", tt.accept, tt.body, got)
+		}
+	}
+}
+
+func TestRespondEncodingError(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := Respond(w, httptest.NewRequest("GET", "/", nil), http.StatusOK, func() {})
+	if err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package server
+
+import "syscall"
+
+// reusePortControl fails, since SO_REUSEPORT is not supported on this
+// platform.
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return ErrReusePortUnsupported
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+)
+
+func TestReusePort(t *testing.T) {
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		})
+	}
+
+	a := New("127.0.0.1:0", handler("a"), WithReusePort())
+	lnA, err := a.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lnA.Addr().String()
+	b := New(addr, handler("b"), WithReusePort())
+	lnB, err := b.listen(addr)
+	if err != nil {
+		lnA.Close()
+		t.Fatalf("expected a second server to bind %s, got %v", addr, err)
+	}
+
+	for _, s := range []*Server{a, b} {
+		s.out = io.Discard
+	}
+	errcA := make(chan error, 1)
+	go func() { errcA <- a.Serve(t.Context(), lnA) }()
+	errcB := make(chan error, 1)
+	go func() { errcB <- b.Serve(t.Context(), lnB) }()
+	defer func() {
+		a.signals <- syscall.SIGTERM
+		b.signals <- syscall.SIGTERM
+		<-errcA
+		<-errcB
+	}()
+
+	// The kernel picks a listener by hashing each connection's addresses, so
+	// enough new connections reach both.
+	c := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	seen := make(map[string]bool)
+	for i := 0; i < 100 && len(seen) < 2; i++ {
+		resp, err := c.Get("http://" + addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		seen[string(body)] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Errorf("expected both servers to accept connections, got %v", seen)
+	}
+}
+
+func TestWithoutReusePort(t *testing.T) {
+	a := New("127.0.0.1:0", okHandler)
+	ln, err := a.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	b := New("", okHandler)
+	if ln, err := b.listen(ln.Addr().String()); !errors.Is(err, syscall.EADDRINUSE) {
+		if err == nil {
+			ln.Close()
+		}
+		t.Errorf("expected the port to be in use without the option, got %v", err)
+	}
+}
@@ -6,17 +6,25 @@
 package server
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
-	"sync"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/frazercomputing/f4/log"
+	"github.com/haleyrc/http/internal/clock"
 )
 
 const (
@@ -44,6 +52,45 @@ type Server struct {
 	server   http.Server
 	shutdown time.Duration
 	out, err io.Writer
+
+	middleware      []func(http.Handler) http.Handler
+	middlewareNames []string
+	metrics         MetricsRecorder
+
+	// signals receives the OS signals that trigger a shutdown. Tests may send
+	// on it directly.
+	signals chan os.Signal
+
+	onServeError func(err error)
+	clock        clock.Clock
+
+	// hijacked holds connections registered with RegisterConn.
+	hijacked *connRegistry
+
+	helloChecks []func(*tls.ClientHelloInfo) error
+
+	conns      *connTracker
+	ready      readiness
+	drainDelay time.Duration
+	drainAck   func(ctx context.Context) error
+
+	accessLogFormat  Format
+	concurrencyQueue time.Duration
+	compressionLevel int
+
+	workers  workerGroup
+	children []*exec.Cmd
+
+	lameDuckSignal os.Signal
+	errorResponder func(w http.ResponseWriter, r *http.Request, status int, cause error)
+	shutdownReason atomic.Value
+
+	unixSocketMode  os.FileMode
+	unixSocketGroup int
+
+	gracefulListenerClose bool
+	listenBacklog         int
+	reusePort             bool
 }
 
 // New returns a new Server with sane timeouts, and the supplied address and
@@ -61,11 +108,25 @@ func New(addr string, h http.Handler, opts ...Option) *Server {
 		shutdown: ShutdownTimeout,
 		out:      os.Stdout,
 		err:      os.Stderr,
+		signals:  make(chan os.Signal, 1),
+		clock:    clock.Real,
+		hijacked: newConnRegistry(),
+		conns:    newConnTracker(),
+
+		compressionLevel: gzip.DefaultCompression,
+		unixSocketGroup:  -1,
 	}
+	s.workers.init()
+	s.onConnContext(func(ctx context.Context, c net.Conn) context.Context {
+		return context.WithValue(ctx, connRegistryKey{}, s.hijacked)
+	})
+	s.onConnState(s.conns.track)
 
 	for _, opt := range opts {
 		s = opt(s)
 	}
+	s.server.Handler = s.withErrorResponder(chain(h, s.middleware...))
+	s.configureTLS()
 
 	return s
 }
@@ -92,6 +153,15 @@ func WithWriteTimeout(to time.Duration) Option {
 	}
 }
 
+// WithIdleTimeout modifies the server to set the idle timeout to the provided
+// value. If zero, the read timeout is used instead.
+func WithIdleTimeout(to time.Duration) Option {
+	return func(s *Server) *Server {
+		s.server.IdleTimeout = to
+		return s
+	}
+}
+
 // WithShutdown modifies the server to set the shutdown timeout to the provided
 // value.
 func WithShutdown(to time.Duration) Option {
@@ -101,6 +171,29 @@ func WithShutdown(to time.Duration) Option {
 	}
 }
 
+// ShutdownFromEnv returns an Option that sets the shutdown timeout from the
+// named environment variable. The value may be a duration string such as "10s"
+// or a bare number of seconds. If the variable is unset, cannot be parsed, or is
+// not positive, the fallback is used instead.
+//
+// This is useful in containers where the orchestrator's termination grace
+// period is passed to the service through the environment.
+func ShutdownFromEnv(key string, fallback time.Duration) Option {
+	to := fallback
+	if d, err := parseSeconds(os.Getenv(key)); err == nil && d > 0 {
+		to = d
+	}
+	return WithShutdown(to)
+}
+
+// parseSeconds parses s as a duration, treating a bare number as seconds.
+func parseSeconds(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
 // WithMaxHeaderBytes modifies the server to set the maximum header bytes to the
 // provided value.
 func WithMaxHeaderBytes(n int) Option {
@@ -110,10 +203,48 @@ func WithMaxHeaderBytes(n int) Option {
 	}
 }
 
+// WithServeErrorHandler modifies the server to call fn, instead of writing to
+// the error writer, when it fails to bind its address or stops serving
+// unexpectedly. The error is still returned from ListenAndServe after fn
+// returns.
+func WithServeErrorHandler(fn func(err error)) Option {
+	return func(s *Server) *Server {
+		s.onServeError = fn
+		return s
+	}
+}
+
+// WithDisableHTTP2 modifies the server to never negotiate HTTP/2 over TLS,
+// forcing clients to use HTTP/1.1.
+func WithDisableHTTP2() Option {
+	return func(s *Server) *Server {
+		s.server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		return s
+	}
+}
+
+// Clock provides the current time and timers to the server. It exists so tests
+// can control time-dependent behavior, and defaults to the system clock.
+type Clock = clock.Clock
+
+// Timer is the timer type returned by a Clock.
+type Timer = clock.Timer
+
+// WithClock modifies the server to use clk for all time-dependent behavior.
+// This is intended for tests.
+func WithClock(clk Clock) Option {
+	return func(s *Server) *Server {
+		s.clock = clk
+		return s
+	}
+}
+
 // WithOutputWriter modifies the server to set the output writer to the provided
 // value.
 //
-// The server uses this writer for non-error messages.
+// The server uses this writer for non-error messages. If w is buffered and has a
+// Flush() error or Sync() error method, such as a *bufio.Writer or *os.File, it
+// is flushed before ListenAndServe and Serve return.
 func WithOutputWriter(w io.Writer) Option {
 	return func(s *Server) *Server {
 		s.out = w
@@ -124,7 +255,8 @@ func WithOutputWriter(w io.Writer) Option {
 // WithErrorWriter modifies the server to set the error writer to the provided
 // value.
 //
-// The server uses this writer for any errors produced by the server.
+// The server uses this writer for any errors produced by the server. It is
+// flushed in the same way as the output writer.
 func WithErrorWriter(w io.Writer) Option {
 	return func(s *Server) *Server {
 		s.err = w
@@ -132,38 +264,209 @@ func WithErrorWriter(w io.Writer) Option {
 	}
 }
 
+// ErrShutdownTimeout is returned by ListenAndServe and Serve when in-flight
+// requests did not finish within the shutdown timeout and the server had to be
+// closed forcefully.
+var ErrShutdownTimeout = errors.New("server: shutdown timed out")
+
+// ShutdownTimeoutError is returned by ListenAndServe and Serve, in place of a
+// plain ErrShutdownTimeout, when the server was closed forcefully with
+// requests still in flight, so that operators can alarm on requests dropped
+// during shutdown. It matches ErrShutdownTimeout with errors.Is.
+type ShutdownTimeoutError struct {
+	// Dropped is the number of connections that were still serving a request
+	// when the server was closed, each of which had its request cut off.
+	Dropped int64
+}
+
+func (e *ShutdownTimeoutError) Error() string {
+	return fmt.Sprintf("%v: %d requests dropped", ErrShutdownTimeout, e.Dropped)
+}
+
+// Unwrap allows errors.Is to match ShutdownTimeoutError against
+// ErrShutdownTimeout.
+func (e *ShutdownTimeoutError) Unwrap() error {
+	return ErrShutdownTimeout
+}
+
+// ErrAddrInUse is returned, wrapped with the address, by ListenAndServe when
+// another process is already listening on the server's address.
+var ErrAddrInUse = errors.New("server: address already in use")
+
+// ErrReusePortUnsupported is returned, wrapped, by ListenAndServe when
+// WithReusePort is used on a platform without SO_REUSEPORT.
+var ErrReusePortUnsupported = errors.New("server: SO_REUSEPORT is not supported on this platform")
+
 // ListenAndServe starts the wrapped server and listens for a number of
 // interrupts which will trigger a shutdown. The shutdown attempts to be
 // graceful and wait for in-flight requests to finish, but will shutdown
 // forcefully if the timeout is exceeded.
+//
+// An address of the form "unix:/path/to/socket" listens on a Unix domain
+// socket at that path instead of a TCP address. See WithUnixSocketMode and
+// WithUnixSocketGroup.
+//
+// The returned error can be used to choose an exit code:
+//
+//   - nil if the server was shut down gracefully, either by a signal or by ctx
+//     being cancelled.
+//   - ErrShutdownTimeout if the shutdown timeout was exceeded and the server
+//     was closed forcefully. If requests were still in flight, it is wrapped
+//     in a *ShutdownTimeoutError reporting how many.
+//   - ErrAddrInUse if another process is already listening on the address.
+//   - Any other error if the server failed to bind its address or stopped
+//     serving unexpectedly.
+//
+// ShutdownReason reports what triggered the shutdown.
 func (s *Server) ListenAndServe(ctx context.Context) error {
 	log.Trace(ctx, "f4/http/server/Server.ListenAndServe")
-	var wg sync.WaitGroup
-	wg.Add(1)
 
+	addr := s.addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := s.listen(addr)
+	if err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			err = fmt.Errorf("%w: %s: %w", ErrAddrInUse, addr, err)
+		}
+		s.serveError(err)
+		s.flushWriters()
+		return err
+	}
+
+	return s.Serve(ctx, ln)
+}
+
+// listen binds addr, which is either a TCP address or a "unix:" socket path.
+func (s *Server) listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		return s.listenUnix(path)
+	}
+	return s.listenTCP(addr)
+}
+
+// Serve is like ListenAndServe, but accepts connections on the provided
+// listener instead of binding the server's address. Serve always closes ln
+// before returning. A panic while accepting connections is returned as an
+// error rather than crashing the process.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	log.Trace(ctx, "f4/http/server/Server.Serve")
+	defer s.flushWriters()
+	defer s.finish()
+
+	fmt.Fprintf(s.out, "listening on %s...\n", ln.Addr())
+	var graceful *gracefulListener
+	if s.gracefulListenerClose {
+		graceful = newGracefulListener(ln)
+		ln = graceful
+	}
+	errc := make(chan error, 1)
 	go func() {
-		defer wg.Done()
-		fmt.Fprintf(s.out, "listening on %s...\n", s.addr)
-		fmt.Fprintf(s.err, s.server.ListenAndServe().Error())
+		defer func() {
+			if r := recover(); r != nil {
+				errc <- fmt.Errorf("server: panic: %v", r)
+			}
+		}()
+		errc <- s.serve(ln)
 	}()
 
-	osSignals := make(chan os.Signal)
-	signal.Notify(osSignals, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	sigs := []os.Signal{os.Interrupt, syscall.SIGINT, syscall.SIGTERM}
+	if s.lameDuckSignal != nil {
+		sigs = append(sigs, s.lameDuckSignal)
+	}
+	signal.Notify(s.signals, sigs...)
+	defer signal.Stop(s.signals)
 
-	<-osSignals
+wait:
+	for {
+		select {
+		case err := <-errc:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			s.setShutdownReason("serve error: " + err.Error())
+			s.serveError(err)
+			s.stopChildren(s.clock.Now().Add(s.shutdown))
+			return err
+		case sig := <-s.signals:
+			if s.enterLameDuck(sig) {
+				continue
+			}
+			s.setShutdownReason(signalReason(sig))
+		case <-ctx.Done():
+			s.setShutdownReason(context.Cause(ctx).Error())
+		}
+		break wait
+	}
+	if graceful != nil {
+		graceful.stopAccepting()
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, s.shutdown)
+	if !s.ready.lameDuck.Load() {
+		s.drain()
+	}
+	s.ready.shuttingDown.Store(true)
+	s.stopWorkers()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.shutdown)
 	defer cancel()
+	s.stopChildren(s.clock.Now().Add(s.shutdown))
+	defer s.hijacked.closeAll()
 
 	if err := s.server.Shutdown(ctx); err != nil {
-		fmt.Fprintf(s.err, "shutdown timed out after %s: %v", s.shutdown, err)
+		dropped := s.conns.Active()
+		fmt.Fprintf(s.err, "shutdown timed out after %s with %d requests in flight: %v\n", s.shutdown, dropped, err)
 		if err := s.server.Close(); err != nil {
-			fmt.Fprintf(s.err, "error killing server: %v", err)
+			fmt.Fprintf(s.err, "error killing server: %v\n", err)
 			return err
 		}
+		<-errc
+		if dropped == 0 {
+			return ErrShutdownTimeout
+		}
+		return &ShutdownTimeoutError{Dropped: dropped}
 	}
 
-	wg.Wait()
+	<-errc
+
+	if err := s.waitWorkers(ctx); err != nil {
+		fmt.Fprintf(s.err, "shutdown timed out after %s waiting for workers\n", s.shutdown)
+		return ErrShutdownTimeout
+	}
 
+	fmt.Fprintln(s.out, "shutdown complete")
 	return nil
 }
+
+// serve accepts connections on ln, using TLS if the server has a TLS config.
+func (s *Server) serve(ln net.Listener) error {
+	if s.server.TLSConfig != nil {
+		return s.server.ServeTLS(ln, "", "")
+	}
+	return s.server.Serve(ln)
+}
+
+// serveError reports an error that stopped the server from serving, either to
+// the configured serve error handler or, by default, the error writer.
+func (s *Server) serveError(err error) {
+	if s.onServeError != nil {
+		s.onServeError(err)
+		return
+	}
+	fmt.Fprintln(s.err, err)
+}
+
+// flushWriters flushes the output and error writers, if they are buffered, so
+// the last messages before the server stops are not lost. Errors are ignored,
+// since there is nowhere left to report them.
+func (s *Server) flushWriters() {
+	for _, w := range []io.Writer{s.out, s.err} {
+		switch w := w.(type) {
+		case interface{ Flush() error }:
+			w.Flush()
+		case interface{ Sync() error }:
+			w.Sync()
+		}
+	}
+}
@@ -1,6 +1,17 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -17,3 +28,222 @@ func TestServerOptions(t *testing.T) {
 		t.Errorf("expected read timeout %s, got %s", to, s.server.ReadTimeout)
 	}
 }
+
+func TestShutdownFromEnv(t *testing.T) {
+	fallback := 3 * time.Second
+	tests := map[string]time.Duration{
+		"":      fallback,
+		"10s":   10 * time.Second,
+		"15":    15 * time.Second,
+		"1m30s": 90 * time.Second,
+		"0":     fallback,
+		"-5s":   fallback,
+		"bogus": fallback,
+	}
+	for val, want := range tests {
+		t.Setenv("SHUTDOWN_TIMEOUT", val)
+		s := New(":8080", nil, ShutdownFromEnv("SHUTDOWN_TIMEOUT", fallback))
+		if s.shutdown != want {
+			t.Errorf("%q: expected shutdown timeout %s, got %s", val, want, s.shutdown)
+		}
+	}
+}
+
+func TestServerDisableHTTP2(t *testing.T) {
+	// Borrow a test certificate and a trusting client from httptest.
+	ts := httptest.NewUnstartedServer(nil)
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	ts.Close()
+	c := ts.Client()
+
+	for _, tt := range []struct {
+		opts  []Option
+		proto string
+	}{
+		{nil, "HTTP/2.0"},
+		{[]Option{WithDisableHTTP2()}, "HTTP/1.1"},
+	} {
+		s := New("127.0.0.1:0", okHandler, tt.opts...)
+		s.server.TLSConfig = &tls.Config{Certificates: ts.TLS.Certificates}
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go s.server.ServeTLS(ln, "", "")
+
+		resp, err := c.Get("https://" + ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		s.server.Close()
+
+		if resp.Proto != tt.proto {
+			t.Errorf("expected protocol %s, got %s", tt.proto, resp.Proto)
+		}
+	}
+}
+
+// start runs s on a random local port and returns its address along with a
+// channel that receives the result of Serve.
+func start(t *testing.T, s *Server) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(context.Background(), ln) }()
+	return ln.Addr().String(), errc
+}
+
+func TestServeCleanShutdown(t *testing.T) {
+	s := New("", okHandler, WithOutputWriter(io.Discard))
+	addr, errc := start(t, s)
+
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestServeFlushesBufferedWriters(t *testing.T) {
+	var out bytes.Buffer
+	bw := bufio.NewWriterSize(&out, 4096)
+	s := New("", okHandler, WithOutputWriter(bw))
+	_, errc := start(t, s)
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "shutdown complete") {
+		t.Errorf("expected output to contain the shutdown message, got %q", out.String())
+	}
+}
+
+func TestServeContextCancelled(t *testing.T) {
+	s := New("", okHandler, WithOutputWriter(io.Discard))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Serve(ctx, ln); err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	s := New("", h, WithShutdown(10*time.Millisecond), WithOutputWriter(io.Discard), WithErrorWriter(io.Discard))
+	addr, errc := start(t, s)
+
+	go http.Get("http://" + addr)
+	<-entered
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; !errors.Is(err, ErrShutdownTimeout) {
+		t.Errorf("expected error %v, got %v", ErrShutdownTimeout, err)
+	}
+}
+
+func TestServeShutdownTimeoutDropped(t *testing.T) {
+	h, entered, release := blocking()
+	defer close(release)
+
+	s := New("", h, WithShutdown(50*time.Millisecond), WithOutputWriter(io.Discard), WithErrorWriter(io.Discard))
+	addr, errc := start(t, s)
+
+	for i := 0; i < 3; i++ {
+		go http.Get("http://" + addr)
+		<-entered
+	}
+
+	s.signals <- syscall.SIGTERM
+	err := <-errc
+	var timeoutErr *ShutdownTimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("expected a *ShutdownTimeoutError, got %v", err)
+	}
+	if timeoutErr.Dropped != 3 {
+		t.Errorf("expected 3 dropped requests, got %d", timeoutErr.Dropped)
+	}
+}
+
+func TestListenAndServeBindError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s := New(ln.Addr().String(), okHandler, WithOutputWriter(io.Discard), WithErrorWriter(io.Discard))
+	err = s.ListenAndServe(context.Background())
+	if !errors.Is(err, ErrAddrInUse) {
+		t.Fatalf("expected error %v, got %v", ErrAddrInUse, err)
+	}
+	if !strings.Contains(err.Error(), ln.Addr().String()) {
+		t.Errorf("expected the error to include the address, got %v", err)
+	}
+}
+
+func TestServeErrorHandler(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var got error
+	s := New(ln.Addr().String(), okHandler,
+		WithOutputWriter(io.Discard),
+		WithServeErrorHandler(func(err error) { got = err }),
+	)
+	err = s.ListenAndServe(context.Background())
+	if err == nil {
+		t.Fatal("expected a bind error")
+	}
+	if got != err {
+		t.Errorf("expected handler to receive %v, got %v", err, got)
+	}
+}
+
+// failingListener is a net.Listener whose Accept always fails with err.
+type failingListener struct {
+	net.Listener
+	err error
+}
+
+func (l failingListener) Accept() (net.Conn, error) { return nil, l.err }
+
+func TestServeErrorWithPercent(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	s := New("", okHandler, WithOutputWriter(io.Discard), WithErrorWriter(&buf))
+	s.Serve(context.Background(), failingListener{ln, errors.New("parse 100%zz: invalid URL escape")})
+
+	if got, want := buf.String(), "parse 100%zz: invalid URL escape\n"; got != want {
+		t.Errorf("expected output %q, got %q", want, got)
+	}
+}
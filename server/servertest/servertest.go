@@ -0,0 +1,220 @@
+// Package servertest provides a harness for testing the shutdown behavior of a
+// server.Server, such as whether in-flight requests complete gracefully or are
+// cut off when the shutdown timeout expires, without signals or real sleeps
+// beyond the shutdown timeout itself.
+package servertest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/internal/clock"
+	"github.com/haleyrc/http/server"
+)
+
+// WaitTimeout bounds how long the harness waits for anything to happen before
+// failing the test, so a broken shutdown fails instead of hanging.
+const WaitTimeout = 5 * time.Second
+
+// Harness runs a server.Server on a local port with a fake clock, and lets a
+// test shut it down and inspect the outcome.
+type Harness struct {
+	// URL is the base URL of the server, such as "http://127.0.0.1:1234".
+	URL string
+
+	t      testing.TB
+	clock  *clock.Fake
+	cancel context.CancelFunc
+	errc   chan error
+
+	mu       sync.Mutex
+	out, err bytes.Buffer
+}
+
+// New creates a server for h with the given options and starts it. The server
+// uses a fake clock, controlled with Advance, and its output and errors are
+// captured for Output and Errors. Options that set the clock or writers are
+// overridden.
+//
+// The server is shut down when the test ends if it is still running.
+func New(t testing.TB, h http.Handler, opts ...server.Option) *Harness {
+	t.Helper()
+	hs := &Harness{t: t, clock: clock.NewFake(time.Now()), errc: make(chan error, 1)}
+	opts = append(opts,
+		server.WithClock(hs.clock),
+		server.WithOutputWriter(lockedWriter{&hs.mu, &hs.out}),
+		server.WithErrorWriter(lockedWriter{&hs.mu, &hs.err}),
+	)
+	s := server.New("", h, opts...)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs.URL = "http://" + ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hs.cancel = cancel
+	go func() { hs.errc <- s.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-hs.errc:
+		case <-time.After(WaitTimeout):
+		}
+	})
+	return hs
+}
+
+// Shutdown triggers a graceful shutdown, as a SIGTERM would, and returns
+// without waiting for it to finish.
+func (hs *Harness) Shutdown() {
+	hs.cancel()
+}
+
+// Wait waits for the server to stop and returns the error from Serve, which is
+// nil for a graceful shutdown and server.ErrShutdownTimeout if in-flight
+// requests were cut off. The test fails if the server does not stop within
+// WaitTimeout.
+func (hs *Harness) Wait() error {
+	hs.t.Helper()
+	select {
+	case err := <-hs.errc:
+		hs.errc <- err
+		return err
+	case <-time.After(WaitTimeout):
+		hs.t.Fatal("servertest: server did not stop")
+		return nil
+	}
+}
+
+// Advance moves the server's fake clock forward by d, firing any timers that
+// expire, such as a drain delay.
+func (hs *Harness) Advance(d time.Duration) {
+	hs.clock.Advance(d)
+}
+
+// WaitForTimers waits until the server has at least n timers pending on its
+// fake clock, such as once a drain delay has started, so that a following
+// Advance fires them.
+func (hs *Harness) WaitForTimers(n int) {
+	hs.t.Helper()
+	deadline := time.Now().Add(WaitTimeout)
+	for hs.clock.Waiters() < n {
+		if time.Now().After(deadline) {
+			hs.t.Fatalf("servertest: expected %d pending timers, got %d", n, hs.clock.Waiters())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Output returns everything the server has written to its output writer.
+func (hs *Harness) Output() string {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.out.String()
+}
+
+// Errors returns everything the server has written to its error writer.
+func (hs *Harness) Errors() string {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.err.String()
+}
+
+// Request is a request sent in the background with Go.
+type Request struct {
+	done chan struct{}
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// Go sends a GET request for path in the background, with a client of its
+// own, and returns immediately.
+func (hs *Harness) Go(path string) *Request {
+	req := &Request{done: make(chan struct{})}
+	c := &http.Client{Transport: &http.Transport{}}
+	go func() {
+		defer close(req.done)
+		defer c.CloseIdleConnections()
+		resp, err := c.Get(hs.URL + path)
+		if err != nil {
+			req.err = err
+			return
+		}
+		defer resp.Body.Close()
+		req.resp = resp
+		req.body, req.err = io.ReadAll(resp.Body)
+	}()
+	return req
+}
+
+// Result is the outcome of a Request.
+type Result struct {
+	// StatusCode and Body are the response received, if the request
+	// completed.
+	StatusCode int
+	Body       []byte
+
+	// Err is the error that stopped the request, such as the connection
+	// being closed when the server was forced to stop.
+	Err error
+}
+
+// Completed reports whether the request received a whole response.
+func (r Result) Completed() bool {
+	return r.Err == nil
+}
+
+// Wait waits for the request to finish and returns its result. The test
+// fails if it does not finish within WaitTimeout.
+func (r *Request) Wait(t testing.TB) Result {
+	t.Helper()
+	select {
+	case <-r.done:
+	case <-time.After(WaitTimeout):
+		t.Fatal("servertest: request did not finish")
+	}
+	res := Result{Body: r.body, Err: r.err}
+	if r.resp != nil {
+		res.StatusCode = r.resp.StatusCode
+	}
+	return res
+}
+
+// Slow returns a handler that blocks until release is called, or the request
+// is cancelled, and then responds with 200 OK. entered is closed once the
+// handler has been called, so a test can wait for a request to be in flight.
+// release may be called more than once.
+func Slow() (h http.Handler, entered <-chan struct{}, release func()) {
+	in, gate := make(chan struct{}), make(chan struct{})
+	var inOnce, releaseOnce sync.Once
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inOnce.Do(func() { close(in) })
+		select {
+		case <-gate:
+			w.Write([]byte("ok"))
+		case <-r.Context().Done():
+		}
+	})
+	return h, in, func() { releaseOnce.Do(func() { close(gate) }) }
+}
+
+// lockedWriter serializes writes to w with mu.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (lw lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
@@ -0,0 +1,63 @@
+package servertest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/http/server"
+	"github.com/haleyrc/http/server/servertest"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	h, entered, release := servertest.Slow()
+	hs := servertest.New(t, h)
+
+	req := hs.Go("/")
+	<-entered
+	hs.Shutdown()
+	release()
+
+	if err := hs.Wait(); err != nil {
+		t.Errorf("expected a graceful shutdown, got %v", err)
+	}
+	res := req.Wait(t)
+	if !res.Completed() || res.StatusCode != http.StatusOK || string(res.Body) != "ok" {
+		t.Errorf("expected the in-flight request to complete, got %+v", res)
+	}
+}
+
+func TestForcedShutdown(t *testing.T) {
+	h, entered, release := servertest.Slow()
+	defer release()
+	hs := servertest.New(t, h, server.WithShutdown(10*time.Millisecond))
+
+	req := hs.Go("/")
+	<-entered
+	hs.Shutdown()
+
+	if err := hs.Wait(); !errors.Is(err, server.ErrShutdownTimeout) {
+		t.Errorf("expected error %v, got %v", server.ErrShutdownTimeout, err)
+	}
+	if res := req.Wait(t); res.Completed() {
+		t.Errorf("expected the in-flight request to be cut off, got %+v", res)
+	}
+}
+
+func TestDrainDelay(t *testing.T) {
+	hs := servertest.New(t, http.NotFoundHandler(), server.WithDrainDelay(time.Minute))
+
+	hs.Shutdown()
+	hs.WaitForTimers(1)
+
+	// New requests are still served during the drain delay.
+	if res := hs.Go("/").Wait(t); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected requests to be served while draining, got %+v", res)
+	}
+
+	hs.Advance(time.Minute)
+	if err := hs.Wait(); err != nil {
+		t.Errorf("expected a graceful shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// WithShutdownResponse modifies the server to reject requests that arrive once
+// the graceful shutdown has begun, such as further requests on a keep-alive
+// connection that was mid-request when shutdown started, with the given
+// response instead of serving them. This lets the rejection match the rest of
+// an API's error format, or tell clients when to retry with a Retry-After
+// header.
+//
+// status must be a 4xx or 5xx code; anything else is replaced with 503
+// Service Unavailable. headers are copied onto every rejection, and a
+// "Connection: close" header is always added. Requests served during a drain
+// delay are not rejected.
+func WithShutdownResponse(status int, body []byte, headers http.Header) Option {
+	if status < 400 || status > 599 {
+		status = http.StatusServiceUnavailable
+	}
+	headers = headers.Clone()
+	return func(s *Server) *Server {
+		s.use("shutdown_response", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !s.ready.shuttingDown.Load() {
+					next.ServeHTTP(w, r)
+					return
+				}
+				hdr := w.Header()
+				for k, vs := range headers {
+					hdr[k] = append([]string(nil), vs...)
+				}
+				hdr.Set("Connection", "close")
+				hdr.Set("Content-Length", strconv.Itoa(len(body)))
+				w.WriteHeader(status)
+				w.Write(body)
+			})
+		})
+		return s
+	}
+}
+
+// ShutdownReason returns why the server last stopped serving: the name of the
+// signal that triggered the shutdown, such as "SIGTERM", the cause of the
+// context passed to Serve being done, such as "context canceled", or
+// "serve error: " followed by the error that stopped the server. It returns
+// "" while the server has not stopped.
+//
+// The reason is also written to the output writer when the shutdown begins.
+func (s *Server) ShutdownReason() string {
+	reason, _ := s.shutdownReason.Load().(string)
+	return reason
+}
+
+// setShutdownReason records reason for ShutdownReason, and reports it.
+func (s *Server) setShutdownReason(reason string) {
+	s.shutdownReason.Store(reason)
+	fmt.Fprintf(s.out, "shutting down: %s\n", reason)
+}
+
+// signalReason returns the shutdown reason for sig.
+func signalReason(sig os.Signal) string {
+	switch sig {
+	case syscall.SIGINT:
+		return "SIGINT"
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	case syscall.SIGHUP:
+		return "SIGHUP"
+	case syscall.SIGQUIT:
+		return "SIGQUIT"
+	}
+	return sig.String()
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestShutdownResponse(t *testing.T) {
+	body := []byte(`{"error":"shutting down"}`)
+	hdr := http.Header{"Content-Type": {"application/json"}, "Retry-After": {"5"}}
+	s := New("", okHandler, WithShutdownResponse(http.StatusServiceUnavailable, body, hdr))
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d before shutdown, got %d", http.StatusOK, w.Code)
+	}
+
+	s.ready.shuttingDown.Store(true)
+	w = serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Body.String(); got != string(body) {
+		t.Errorf("expected body %q, got %q", body, got)
+	}
+	if got := w.Header().Get("Retry-After"); got != "5" {
+		t.Errorf("expected Retry-After 5, got %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected JSON content type, got %q", got)
+	}
+	if got := w.Header().Get("Connection"); got != "close" {
+		t.Errorf("expected Connection close, got %q", got)
+	}
+}
+
+func TestShutdownResponseInvalidStatus(t *testing.T) {
+	s := New("", okHandler, WithShutdownResponse(http.StatusOK, nil, nil))
+	s.ready.shuttingDown.Store(true)
+
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestShutdownReason(t *testing.T) {
+	var out bytes.Buffer
+	s := New("", okHandler, WithOutputWriter(&out))
+	if reason := s.ShutdownReason(); reason != "" {
+		t.Errorf("expected no reason before shutdown, got %q", reason)
+	}
+	_, errc := start(t, s)
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if reason := s.ShutdownReason(); reason != "SIGTERM" {
+		t.Errorf("expected reason SIGTERM, got %q", reason)
+	}
+	if !strings.Contains(out.String(), "shutting down: SIGTERM") {
+		t.Errorf("expected the reason to be logged, got %q", out.String())
+	}
+}
+
+func TestShutdownReasonContext(t *testing.T) {
+	s := New("", okHandler, WithOutputWriter(io.Discard))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Serve(ctx, ln); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if reason := s.ShutdownReason(); reason != "context canceled" {
+		t.Errorf("expected reason %q, got %q", "context canceled", reason)
+	}
+}
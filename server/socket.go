@@ -0,0 +1,36 @@
+package server
+
+import "os"
+
+// unixPrefix marks a server address as the path of a Unix domain socket.
+const unixPrefix = "unix:"
+
+// WithUnixSocketMode modifies the server to set the permissions of its Unix
+// socket file to mode, such as 0o660 to let a group of peer processes connect.
+// It has no effect unless the server listens on a "unix:" address.
+//
+// So that no peer can connect before the permissions are set, the socket is
+// created with a umask that leaves it accessible only to its owner, and then
+// changed to mode before the server starts accepting connections. The umask is
+// process wide, so files created by other goroutines while the socket is being
+// created are restricted too.
+func WithUnixSocketMode(mode os.FileMode) Option {
+	return func(s *Server) *Server {
+		s.unixSocketMode = mode.Perm()
+		return s
+	}
+}
+
+// WithUnixSocketGroup modifies the server to set the group that owns its Unix
+// socket file to gid before it starts accepting connections. The process must
+// be a member of the group, or be privileged. It has no effect unless the
+// server listens on a "unix:" address.
+//
+// The group can only connect if the socket's mode allows it, so this is
+// normally combined with WithUnixSocketMode.
+func WithUnixSocketGroup(gid int) Option {
+	return func(s *Server) *Server {
+		s.unixSocketGroup = gid
+		return s
+	}
+}
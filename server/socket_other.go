@@ -0,0 +1,17 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+)
+
+// listenUnix listens on a Unix domain socket at path. Setting the socket's
+// mode or group is not supported on this platform.
+func (s *Server) listenUnix(path string) (net.Listener, error) {
+	if s.unixSocketMode != 0 || s.unixSocketGroup >= 0 {
+		return nil, errors.New("server: unix socket mode and group are not supported on this platform")
+	}
+	return net.Listen("unix", path)
+}
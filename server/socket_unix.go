@@ -0,0 +1,40 @@
+//go:build unix
+
+package server
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenUnix listens on a Unix domain socket at path, applying the configured
+// mode and group before returning the listener.
+func (s *Server) listenUnix(path string) (net.Listener, error) {
+	var ln net.Listener
+	var err error
+	if s.unixSocketMode != 0 {
+		old := syscall.Umask(0o177)
+		ln, err = net.Listen("unix", path)
+		syscall.Umask(old)
+	} else {
+		ln, err = net.Listen("unix", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.unixSocketGroup >= 0 {
+		if err := os.Chown(path, -1, s.unixSocketGroup); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	if s.unixSocketMode != 0 {
+		if err := os.Chmod(path, s.unixSocketMode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
@@ -0,0 +1,93 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// socketPath returns a path for a Unix socket in a new temporary directory,
+// which is kept short since socket paths are limited to around 100 bytes.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "s")
+}
+
+func TestUnixSocketMode(t *testing.T) {
+	path := socketPath(t)
+	s := New(unixPrefix+path, okHandler,
+		WithUnixSocketMode(0o660),
+		WithUnixSocketGroup(os.Getgid()),
+		WithOutputWriter(io.Discard),
+	)
+	ln, err := s.listen(s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Errorf("expected a socket, got mode %s", info.Mode())
+	}
+	if perm := info.Mode().Perm(); perm != 0o660 {
+		t.Errorf("expected permissions %o, got %o", 0o660, perm)
+	}
+	if gid := info.Sys().(*syscall.Stat_t).Gid; int(gid) != os.Getgid() {
+		t.Errorf("expected group %d, got %d", os.Getgid(), gid)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(context.Background(), ln) }()
+
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := c.Get("http://unix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestUnixSocketRestrictiveMode(t *testing.T) {
+	path := socketPath(t)
+	s := New(unixPrefix+path, okHandler, WithUnixSocketMode(0o600))
+	ln, err := s.listen(s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("expected permissions %o, got %o", 0o600, perm)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// StaticOptions configures the handler returned by StaticFS.
+type StaticOptions struct {
+	// Fallback is the file served, with a 200 status, for any path that does
+	// not match a file. Set it to "index.html" for single-page applications
+	// that do their own client-side routing. If empty, unmatched paths get a
+	// 404.
+	Fallback string
+
+	// Immutable reports whether the named file is fingerprinted, meaning its
+	// name changes whenever its content does. Immutable files are served with
+	// a long-lived Cache-Control header; all others must be revalidated on
+	// every use. If nil, files with a segment of eight or more hex digits in
+	// their name, such as "app.3f2a1b9c.js", are treated as immutable.
+	Immutable func(name string) bool
+}
+
+const (
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "no-cache"
+)
+
+var fingerprintRE = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.`)
+
+func isFingerprinted(name string) bool {
+	return fingerprintRE.MatchString(path.Base(name))
+}
+
+// StaticFS returns a handler that serves the files in fsys, such as an
+// embed.FS containing a web UI. In addition to what http.FileServerFS does, it:
+//
+//   - sets a strong ETag for every file and answers conditional requests,
+//   - sets Cache-Control according to whether the file is fingerprinted,
+//   - serves a precompressed "name.gz" sibling, if present, to clients that
+//     accept gzip, and
+//   - optionally serves a fallback file for unmatched paths.
+//
+// Directory listings are never served; a request for a directory serves its
+// index.html, if present.
+//
+// ETags are cached for the life of the handler, keyed by each file's name,
+// modification time and size, so a file that changes on disk, as with
+// os.DirFS, gets a new ETag. A change that keeps both the modification time
+// and the size is not noticed, which is only safe for a file system whose
+// files never change, such as an embed.FS.
+func StaticFS(fsys fs.FS, opts StaticOptions) http.Handler {
+	if opts.Immutable == nil {
+		opts.Immutable = isFingerprinted
+	}
+	return &staticHandler{fsys: fsys, opts: opts}
+}
+
+type staticHandler struct {
+	fsys  fs.FS
+	opts  StaticOptions
+	etags sync.Map // etagKey -> string
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, r, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" || strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+
+	err := h.serveFile(w, r, name)
+	if errors.Is(err, fs.ErrNotExist) && h.opts.Fallback != "" {
+		err = h.serveFile(w, r, h.opts.Fallback)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist):
+		writeError(w, r, http.StatusNotFound, ErrNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		writeError(w, r, http.StatusForbidden, err)
+	default:
+		writeError(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// serveFile serves the named file, or its gzipped sibling if the client
+// accepts it. It returns fs.ErrNotExist, without writing anything, if there is
+// no such file.
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) error {
+	if !fs.ValidPath(name) {
+		return fs.ErrNotExist
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return h.serveFile(w, r, path.Join(name, "index.html"))
+	}
+
+	served := name
+	if acceptsGzip(r) {
+		if gz, err := fs.Stat(h.fsys, name+".gz"); err == nil && !gz.IsDir() {
+			served, info = name+".gz", gz
+		}
+	}
+
+	content, err := h.open(served)
+	if err != nil {
+		return err
+	}
+	if c, ok := content.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	etag, err := h.etag(served, info, content)
+	if err != nil {
+		return err
+	}
+
+	hdr := w.Header()
+	if served != name {
+		hdr.Set("Content-Encoding", "gzip")
+	}
+	hdr.Add("Vary", "Accept-Encoding")
+	hdr.Set("ETag", etag)
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		hdr.Set("Content-Type", ct)
+	}
+	if h.opts.Immutable(name) {
+		hdr.Set("Cache-Control", immutableCacheControl)
+	} else {
+		hdr.Set("Cache-Control", revalidateCacheControl)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+	return nil
+}
+
+// open returns the named file as an io.ReadSeeker, reading it into memory if
+// the file system does not provide seekable files.
+func (h *staticHandler) open(name string) (io.ReadSeeker, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if rs, ok := f.(io.ReadSeeker); ok {
+		return rs, nil
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// etagKey identifies a version of a file. A file whose modification time or
+// size changes gets a new key, so its ETag is computed again.
+type etagKey struct {
+	name    string
+	modTime int64
+	size    int64
+}
+
+// etag returns the strong ETag for the named file, computing it from content
+// the first time this version of it, as described by info, is requested.
+// content is rewound before returning.
+func (h *staticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := etagKey{name: name, modTime: info.ModTime().UnixNano(), size: info.Size()}
+	if etag, ok := h.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+	h.etags.Store(key, etag)
+	return etag, nil
+}
+
+// acceptsGzip reports whether the client is willing to accept a gzip-encoded
+// response.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range parseQList(r.Header.Get("Accept-Encoding")) {
+		if enc.value == "gzip" {
+			return enc.q > 0
+		}
+	}
+	return false
+}
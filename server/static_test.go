@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var staticFS = fstest.MapFS{
+	"index.html":         {Data: []byte("<html>app</html>")},
+	"app.3f2a1b9c.js":    {Data: []byte("console.log('app')")},
+	"app.3f2a1b9c.js.gz": {Data: []byte("gzipped")},
+	"style.css":          {Data: []byte("body {}")},
+}
+
+func TestStaticFSETag(t *testing.T) {
+	h := StaticFS(staticFS, StaticOptions{})
+
+	w := serve(h, httptest.NewRequest("GET", "/style.css", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/css; charset=utf-8" {
+		t.Errorf("expected css content type, got %q", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != revalidateCacheControl {
+		t.Errorf("expected Cache-Control %q, got %q", revalidateCacheControl, got)
+	}
+
+	r := httptest.NewRequest("GET", "/style.css", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = serve(h, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+}
+
+func TestStaticFSETagChanged(t *testing.T) {
+	fsys := fstest.MapFS{"style.css": {Data: []byte("body {}"), ModTime: time.Unix(1, 0)}}
+	h := StaticFS(fsys, StaticOptions{})
+	etag := serve(h, httptest.NewRequest("GET", "/style.css", nil)).Header().Get("ETag")
+
+	fsys["style.css"] = &fstest.MapFile{Data: []byte("body {color: red}"), ModTime: time.Unix(2, 0)}
+	r := httptest.NewRequest("GET", "/style.css", nil)
+	r.Header.Set("If-None-Match", etag)
+	w := serve(h, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d for a changed file, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("ETag"); got == etag {
+		t.Errorf("expected a new ETag for a changed file, got the old one %s", got)
+	}
+}
+
+func TestStaticFSFingerprinted(t *testing.T) {
+	h := StaticFS(staticFS, StaticOptions{})
+
+	r := httptest.NewRequest("GET", "/app.3f2a1b9c.js", nil)
+	r.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := serve(h, r)
+	if got := w.Header().Get("Cache-Control"); got != immutableCacheControl {
+		t.Errorf("expected Cache-Control %q, got %q", immutableCacheControl, got)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("expected gzip encoding, got %q", got)
+	}
+	if got := w.Body.String(); got != "gzipped" {
+		t.Errorf("expected the precompressed body, got %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/javascript; charset=utf-8" {
+		t.Errorf("expected javascript content type, got %q", got)
+	}
+}
+
+func TestStaticFSFallback(t *testing.T) {
+	w := serve(StaticFS(staticFS, StaticOptions{}), httptest.NewRequest("GET", "/users/42", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d without fallback, got %d", http.StatusNotFound, w.Code)
+	}
+
+	h := StaticFS(staticFS, StaticOptions{Fallback: "index.html"})
+	w = serve(h, httptest.NewRequest("GET", "/users/42", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Body.String(); got != "<html>app</html>" {
+		t.Errorf("expected index.html, got %q", got)
+	}
+}
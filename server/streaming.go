@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"time"
+)
+
+// StreamingIdleTimeout is the idle timeout used by StreamingDefaults.
+const StreamingIdleTimeout = 60 * time.Second
+
+// StreamingDefaults returns options suited to servers with long-lived
+// streaming responses, such as server-sent events. The global write timeout is
+// disabled, and an idle timeout is set so idle keep-alive connections are still
+// reaped.
+//
+// WARNING: with these options, the server itself places no bound on how long
+// writing a response may take. Every route should be wrapped with WriteDeadline,
+// or otherwise set its own deadline, or a slow client can hold a connection
+// open forever.
+func StreamingDefaults() []Option {
+	return []Option{
+		WithWriteTimeout(0),
+		WithIdleTimeout(StreamingIdleTimeout),
+	}
+}
+
+// WriteDeadline returns a middleware that sets a write deadline of d from the
+// start of each request. It is intended for use with StreamingDefaults, where
+// each route must opt into a deadline appropriate for it.
+//
+// If the underlying ResponseWriter does not support deadlines, the request is
+// served without one.
+func WriteDeadline(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(d))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestContextTimeout returns a middleware that cancels each request's
+// context d after the request starts. Unlike http.TimeoutHandler, it neither
+// buffers the response nor writes one of its own on expiry, so handlers can
+// stream and, once the deadline passes, stop and finish whatever partial
+// response suits them.
+//
+// The timeout only takes effect if handlers watch for it: a handler must check
+// r.Context().Err(), or pass the context to the calls it makes, and return
+// once the context is done. A handler that ignores its context runs to
+// completion as if there were no timeout.
+func RequestContextTimeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WithRequestContextTimeout modifies the server to cancel the context of every
+// request d after it starts. See RequestContextTimeout.
+func WithRequestContextTimeout(d time.Duration) Option {
+	return func(s *Server) *Server {
+		s.use("request_context_timeout", RequestContextTimeout(d))
+		return s
+	}
+}
+
+// WithRouteTimeouts modifies the server to cancel the context of each request
+// after the timeout configured for its route, as with RequestContextTimeout.
+// The keys of timeouts are patterns registered with the server's
+// http.ServeMux, exactly as passed to Handle, and the entry with the key "",
+// if any, applies to requests whose route has no entry of its own. Requests
+// for routes without a timeout, when there is no default, are left alone.
+//
+// Routes are resolved with ServeMux.Handler, so this only works if the handler
+// passed to New is an *http.ServeMux; otherwise only the default applies.
+func WithRouteTimeouts(timeouts map[string]time.Duration) Option {
+	return func(s *Server) *Server {
+		timeouts := maps.Clone(timeouts)
+		mux, _ := s.server.Handler.(*http.ServeMux)
+		s.use("route_timeouts", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var pattern string
+				if mux != nil {
+					_, pattern = mux.Handler(r)
+				}
+				d, ok := timeouts[pattern]
+				if !ok {
+					d, ok = timeouts[""]
+				}
+				if !ok {
+					next.ServeHTTP(w, r)
+					return
+				}
+				ctx, cancel := context.WithTimeout(r.Context(), d)
+				defer cancel()
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+		return s
+	}
+}
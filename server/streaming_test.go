@@ -0,0 +1,118 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamingDefaults(t *testing.T) {
+	s := New(":8080", nil, StreamingDefaults()...)
+	if s.server.WriteTimeout != 0 {
+		t.Errorf("expected write timeout 0, got %s", s.server.WriteTimeout)
+	}
+	if s.server.IdleTimeout == 0 {
+		t.Error("expected a nonzero idle timeout")
+	}
+}
+
+func TestWriteDeadline(t *testing.T) {
+	h := WriteDeadline(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		http.NewResponseController(w).Flush()
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("second"))
+	}))
+	s := New("", h, append(StreamingDefaults(), WithOutputWriter(io.Discard))...)
+	addr, _ := start(t, s)
+	defer s.server.Close()
+
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("expected the stream to be cut off, got %q", body)
+	}
+}
+
+func TestRequestContextTimeout(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		select {
+		case <-r.Context().Done():
+			w.Write([]byte(" cancelled"))
+		case <-time.After(5 * time.Second):
+			w.Write([]byte(" finished"))
+		}
+	})
+	s := New("", h, WithRequestContextTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	w := serve(s.server.Handler, httptest.NewRequest("GET", "/", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the handler to return early, took %s", elapsed)
+	}
+	if got := w.Body.String(); got != "partial cancelled" {
+		t.Errorf("expected the handler's partial response, got %q", got)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	// Each handler waits for its context to be cancelled, and reports how
+	// long that took.
+	waitForTimeout := func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		select {
+		case <-r.Context().Done():
+			io.WriteString(w, time.Since(start).String())
+		case <-time.After(time.Second):
+			io.WriteString(w, "none")
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /fast", waitForTimeout)
+	mux.HandleFunc("GET /slow/{id}", waitForTimeout)
+	mux.HandleFunc("GET /other", waitForTimeout)
+
+	s := New("", mux, WithRouteTimeouts(map[string]time.Duration{
+		"GET /fast":      20 * time.Millisecond,
+		"GET /slow/{id}": 100 * time.Millisecond,
+		"":               50 * time.Millisecond,
+	}))
+
+	for _, tt := range []struct {
+		path string
+		want time.Duration
+	}{
+		{"/fast", 20 * time.Millisecond},
+		{"/slow/42", 100 * time.Millisecond},
+		{"/other", 50 * time.Millisecond},
+	} {
+		w := serve(s.server.Handler, httptest.NewRequest("GET", tt.path, nil))
+		got, err := time.ParseDuration(w.Body.String())
+		if err != nil || got < tt.want || got > tt.want+25*time.Millisecond {
+			t.Errorf("%s: expected timeout after %s, got %s", tt.path, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestRouteTimeoutsNoDefault(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /untimed", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("expected no deadline for a route without a timeout")
+		}
+	})
+
+	s := New("", mux, WithRouteTimeouts(map[string]time.Duration{"GET /fast": time.Millisecond}))
+	serve(s.server.Handler, httptest.NewRequest("GET", "/untimed", nil))
+}
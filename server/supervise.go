@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RestartPolicy configures how Supervise restarts a server that stopped
+// unexpectedly.
+type RestartPolicy struct {
+	// MaxAttempts is the maximum number of times the server is started,
+	// including the first. If zero, 5 is used.
+	MaxAttempts int
+
+	// MinBackoff and MaxBackoff bound the delay before each restart. The
+	// delay doubles from MinBackoff after each failure, and is capped at
+	// MaxBackoff. If zero, 100ms and 10s are used.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Supervise runs the server returned by newServer with ListenAndServe, and if
+// it stops unexpectedly, including by panicking, starts a new one from
+// newServer after a backoff. A new Server is created for every attempt since a
+// Server cannot be reused once it has stopped.
+//
+// Supervise returns nil once a server shuts down gracefully, by a signal or by
+// ctx being done, and ErrShutdownTimeout if a shutdown timed out, since
+// neither is a failure to restart from. If ctx is done during a backoff,
+// Supervise returns nil without starting another server. Otherwise, once
+// policy.MaxAttempts servers have failed, the last error is returned.
+//
+// Each failure is written to the failed server's error writer, along with the
+// delay before the restart.
+func Supervise(ctx context.Context, newServer func() *Server, policy RestartPolicy) error {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 5
+	}
+	if policy.MinBackoff <= 0 {
+		policy.MinBackoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 10 * time.Second
+	}
+
+	backoff := policy.MinBackoff
+	for attempt := 1; ; attempt++ {
+		s := newServer()
+		err := s.run(ctx)
+		if err == nil || errors.Is(err, ErrShutdownTimeout) {
+			return err
+		}
+		if attempt == policy.MaxAttempts {
+			return err
+		}
+
+		fmt.Fprintf(s.err, "server stopped unexpectedly, restarting in %s: %v\n", backoff, err)
+		select {
+		case <-s.clock.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		backoff = min(2*backoff, policy.MaxBackoff)
+	}
+}
+
+// run calls ListenAndServe, converting a panic into an error.
+func (s *Server) run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("server: panic: %v", r)
+		}
+	}()
+	return s.ListenAndServe(ctx)
+}
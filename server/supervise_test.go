@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSupervise(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := busy.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first server fails because the port is busy. The port is freed
+	// before the second is created, and once it is serving, cancelling ctx
+	// stops Supervise.
+	starts := 0
+	newServer := func() *Server {
+		starts++
+		if starts == 2 {
+			busy.Close()
+			go func() {
+				for {
+					resp, err := http.Get("http://" + addr)
+					if err == nil {
+						resp.Body.Close()
+						cancel()
+						return
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}()
+		}
+		return New(addr, okHandler, WithOutputWriter(io.Discard), WithErrorWriter(io.Discard))
+	}
+
+	err = Supervise(ctx, newServer, RestartPolicy{MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if starts != 2 {
+		t.Errorf("expected 2 starts, got %d", starts)
+	}
+}
+
+func TestSuperviseMaxAttempts(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	starts := 0
+	newServer := func() *Server {
+		starts++
+		return New(busy.Addr().String(), okHandler, WithOutputWriter(io.Discard), WithErrorWriter(io.Discard))
+	}
+
+	err = Supervise(context.Background(), newServer, RestartPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond})
+	if !errors.Is(err, ErrAddrInUse) {
+		t.Errorf("expected error %v, got %v", ErrAddrInUse, err)
+	}
+	if starts != 3 {
+		t.Errorf("expected 3 starts, got %d", starts)
+	}
+}
+
+func TestSupervisePanic(t *testing.T) {
+	starts := 0
+	newServer := func() *Server {
+		starts++
+		return New("127.0.0.1:-1", okHandler,
+			WithServeErrorHandler(func(err error) { panic("boom") }),
+			WithErrorWriter(io.Discard),
+		)
+	}
+
+	err := Supervise(context.Background(), newServer, RestartPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+	if err == nil || err.Error() != "server: panic: boom" {
+		t.Errorf("expected the panic as an error, got %v", err)
+	}
+	if starts != 2 {
+		t.Errorf("expected 2 starts, got %d", starts)
+	}
+}
+
+func TestSuperviseServePanic(t *testing.T) {
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.Addr().String()
+	free.Close()
+
+	// Connections reach ConnContext on the serve goroutine, so a panic there
+	// must stop the server rather than the test binary.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if c, err := net.Dial("tcp", addr); err == nil {
+				c.Close()
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	starts := 0
+	newServer := func() *Server {
+		starts++
+		s := New(addr, okHandler, WithOutputWriter(io.Discard), WithErrorWriter(io.Discard))
+		s.onConnContext(func(ctx context.Context, c net.Conn) context.Context { panic("boom") })
+		return s
+	}
+
+	err = Supervise(context.Background(), newServer, RestartPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})
+	if err == nil || err.Error() != "server: panic: boom" {
+		t.Errorf("expected the panic as an error, got %v", err)
+	}
+	if starts != 2 {
+		t.Errorf("expected 2 starts, got %d", starts)
+	}
+}
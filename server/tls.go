@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"slices"
+)
+
+// WithTLSConfig modifies the server to serve TLS using a copy of cfg. The
+// config must provide a certificate, through Certificates, GetCertificate, or
+// GetConfigForClient.
+//
+// This replaces any TLS config set by earlier options, so it should come first
+// among the TLS-related options.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *Server) *Server {
+		s.server.TLSConfig = cfg.Clone()
+		return s
+	}
+}
+
+// WithGetConfigForClient modifies the server to call fn with each ClientHello
+// to select the TLS config for that connection, typically based on the SNI
+// server name. This allows a single server to host several names with
+// different certificates or settings.
+//
+// fn is set as GetConfigForClient on the server's TLS config, creating one if
+// WithTLSConfig was not used. If fn returns a non-nil config, that config is
+// used for the connection in its entirety, including its own Certificates or
+// GetCertificate. If it returns nil, the server's config is used, and its
+// GetCertificate is consulted as usual.
+func WithGetConfigForClient(fn func(*tls.ClientHelloInfo) (*tls.Config, error)) Option {
+	return func(s *Server) *Server {
+		s.tlsConfig().GetConfigForClient = fn
+		return s
+	}
+}
+
+// tlsConfig returns the server's TLS config for options to modify, creating
+// an empty one if necessary.
+func (s *Server) tlsConfig() *tls.Config {
+	if s.server.TLSConfig == nil {
+		s.server.TLSConfig = new(tls.Config)
+	}
+	return s.server.TLSConfig
+}
+
+// ErrLegacyTLS is returned from the TLS handshake, and logged to the server's
+// error log, when a client does not offer TLS 1.2 or later and the server was
+// configured with WithRejectLegacyTLS.
+var ErrLegacyTLS = errors.New("server: client does not support TLS 1.2 or later")
+
+// ErrNoALPN is returned from the TLS handshake, and logged to the server's
+// error log, when a client does not offer any of the protocols required by
+// WithRequireALPN.
+var ErrNoALPN = errors.New("server: client offered no required application protocol")
+
+// WithRejectLegacyTLS modifies the server to inspect each ClientHello and fail
+// the handshake with ErrLegacyTLS if the client does not offer at least TLS
+// 1.2. This produces a specific, actionable error in the error log, rather than
+// a generic handshake failure.
+func WithRejectLegacyTLS() Option {
+	return func(s *Server) *Server {
+		s.helloChecks = append(s.helloChecks, func(hello *tls.ClientHelloInfo) error {
+			if slices.ContainsFunc(hello.SupportedVersions, func(v uint16) bool { return v >= tls.VersionTLS12 }) {
+				return nil
+			}
+			return ErrLegacyTLS
+		})
+		return s
+	}
+}
+
+// WithRequireALPN modifies the server to fail the handshake with ErrNoALPN if
+// the client does not offer at least one of the provided application protocols,
+// such as "h2" or "http/1.1".
+func WithRequireALPN(protos ...string) Option {
+	return func(s *Server) *Server {
+		s.helloChecks = append(s.helloChecks, func(hello *tls.ClientHelloInfo) error {
+			for _, p := range hello.SupportedProtos {
+				if slices.Contains(protos, p) {
+					return nil
+				}
+			}
+			return ErrNoALPN
+		})
+		return s
+	}
+}
+
+// configureTLS installs any ClientHello checks ahead of the config's own
+// GetConfigForClient, if any. It is called once all options have been applied,
+// so the checks apply regardless of option order.
+func (s *Server) configureTLS() {
+	if len(s.helloChecks) == 0 {
+		return
+	}
+	cfg := s.tlsConfig()
+	next := cfg.GetConfigForClient
+	checks := s.helloChecks
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		for _, check := range checks {
+			if err := check(hello); err != nil {
+				return nil, err
+			}
+		}
+		if next == nil {
+			return nil, nil
+		}
+		return next(hello)
+	}
+}
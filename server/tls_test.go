@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newCert returns a self-signed certificate for the provided host names.
+func newCert(t *testing.T, hosts ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// peerName dials addr with the given SNI name and returns the first DNS name
+// in the certificate the server presents.
+func peerName(t *testing.T, addr, sni string) string {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: sni, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].DNSNames[0]
+}
+
+func TestGetConfigForClient(t *testing.T) {
+	configs := map[string]*tls.Config{
+		"a.example.com": {Certificates: []tls.Certificate{newCert(t, "a.example.com")}},
+		"b.example.com": {Certificates: []tls.Certificate{newCert(t, "b.example.com")}},
+	}
+	s := New("", okHandler,
+		WithOutputWriter(io.Discard),
+		WithGetConfigForClient(func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			return configs[hello.ServerName], nil
+		}),
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.serve(ln)
+	defer s.server.Close()
+
+	for _, name := range []string{"a.example.com", "b.example.com"} {
+		if got := peerName(t, ln.Addr().String(), name); got != name {
+			t.Errorf("expected certificate for %s, got %s", name, got)
+		}
+	}
+}
+
+func TestRejectLegacyTLS(t *testing.T) {
+	rec := new(handshakeRecorder)
+	s := New("", okHandler,
+		WithOutputWriter(io.Discard),
+		WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{newCert(t, "example.com")}}),
+		WithRejectLegacyTLS(),
+		WithRequireALPN("h2", "http/1.1"),
+		WithErrorLogger(NopLogger()),
+		WithMetrics(rec),
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.serve(ln)
+	defer s.server.Close()
+
+	tests := []struct {
+		cfg  *tls.Config
+		kind string
+	}{
+		{&tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11, NextProtos: []string{"http/1.1"}}, "legacy_tls"},
+		{&tls.Config{NextProtos: []string{"spdy/3"}}, "no_alpn_protocol"},
+		{&tls.Config{NextProtos: []string{"http/1.1"}}, ""},
+	}
+	for _, tt := range tests {
+		tt.cfg.InsecureSkipVerify = true
+		conn, err := tls.Dial("tcp", ln.Addr().String(), tt.cfg)
+		if tt.kind == "" {
+			if err != nil {
+				t.Errorf("expected handshake to succeed, got %v", err)
+			} else {
+				conn.Close()
+			}
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Errorf("expected handshake to fail with %s", tt.kind)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.Kinds()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := rec.Kinds(); !slices.Equal(got, []string{"legacy_tls", "no_alpn_protocol"}) {
+		t.Errorf("expected kinds [legacy_tls no_alpn_protocol], got %v", got)
+	}
+}
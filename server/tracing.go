@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// ServerTracer starts a span for every request, giving a tracing library such
+// as OpenTelemetry a seam to hook into without this package depending on it.
+// Implementations must be safe for concurrent use.
+type ServerTracer interface {
+	// StartSpan starts a span for r, typically as a child of any trace
+	// context extracted from r's headers, and returns the context to serve
+	// the request with, carrying the span, and a function that ends it with
+	// the final status code of the response.
+	StartSpan(ctx context.Context, r *http.Request) (context.Context, func(status int))
+}
+
+// WithServerTracing modifies the server to start a span with tracer for every
+// request, ending it with the status sent once the handler returns. The
+// handler is served with the context returned by the tracer, so passing
+// r.Context() to outbound client calls continues the trace. If the handler
+// panics, the span is ended with 500 before the panic continues.
+func WithServerTracing(tracer ServerTracer) Option {
+	return func(s *Server) *Server {
+		s.use("server_tracing", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx, end := tracer.StartSpan(r.Context(), r)
+				sw := &statusWriter{ResponseWriter: w}
+				defer func() {
+					if v := recover(); v != nil {
+						end(http.StatusInternalServerError)
+						panic(v)
+					}
+					end(sw.Status())
+				}()
+				next.ServeHTTP(sw, r.WithContext(ctx))
+			})
+		})
+		return s
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type spanKey struct{}
+
+// fakeTracer starts spans that continue the trace ID in the incoming
+// Traceparent header, recording each when it ends.
+type fakeTracer struct {
+	mu    sync.Mutex
+	ended []string
+}
+
+func (tr *fakeTracer) StartSpan(ctx context.Context, r *http.Request) (context.Context, func(int)) {
+	span := "span of " + r.Header.Get("Traceparent")
+	return context.WithValue(ctx, spanKey{}, span), func(status int) {
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+		tr.ended = append(tr.ended, fmt.Sprintf("%s: %d", span, status))
+	}
+}
+
+func TestServerTracing(t *testing.T) {
+	tracer := &fakeTracer{}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusTeapot)
+		fmt.Fprint(w, r.Context().Value(spanKey{}))
+	})
+	s := New(":8080", h, WithServerTracing(tracer))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Traceparent", "abc")
+	w := serve(s.server.Handler, r)
+	if got := w.Body.String(); got != "span of abc" {
+		t.Errorf("expected the span to reach the handler, got %q", got)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to continue")
+			}
+		}()
+		r := httptest.NewRequest("GET", "/panic", nil)
+		r.Header.Set("Traceparent", "def")
+		serve(s.server.Handler, r)
+	}()
+
+	want := "[span of abc: 418 span of def: 500]"
+	if got := fmt.Sprint(tracer.ended); got != want {
+		t.Errorf("expected ended spans %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// workerGroup tracks the background workers started with Run.
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	served chan struct{} // closed once Serve has returned
+	once   sync.Once
+
+	mu   sync.Mutex
+	errs []error
+}
+
+func (g *workerGroup) init() {
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+	g.served = make(chan struct{})
+}
+
+// Context returns a context that is cancelled when the server begins shutting
+// down, after any drain delay, or stops serving for any other reason.
+func (s *Server) Context() context.Context {
+	return s.workers.ctx
+}
+
+// Run starts fn in a new goroutine as a background worker whose lifetime is
+// tied to the server's, such as a queue consumer or scheduler. fn's context is
+// cancelled when the server begins shutting down, at the same time as the
+// server stops accepting connections, and the server waits for fn to return
+// before Serve or ListenAndServe does. Both share the shutdown timeout: if the
+// workers have not all returned by the time it expires, Serve returns
+// ErrShutdownTimeout without waiting any longer.
+//
+// An error returned by fn does not stop the server, but is reported by Wait.
+// Errors caused by the context being cancelled are ignored.
+func (s *Server) Run(fn func(ctx context.Context) error) {
+	g := &s.workers
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(g.ctx); err != nil && !errors.Is(err, context.Canceled) {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until Serve or ListenAndServe has returned and every worker
+// started with Run has finished, and returns the errors returned by the
+// workers, joined with errors.Join.
+func (s *Server) Wait() error {
+	g := &s.workers
+	<-g.served
+	g.wg.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
+
+// stopWorkers cancels the context passed to workers.
+func (s *Server) stopWorkers() {
+	s.workers.cancel()
+}
+
+// waitWorkers waits for every worker to return, or for ctx to be done.
+func (s *Server) waitWorkers(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.workers.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish is called when Serve returns, to stop any workers still running and
+// release callers of Wait.
+func (s *Server) finish() {
+	s.workers.once.Do(func() {
+		s.workers.cancel()
+		close(s.workers.served)
+	})
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunWorkers(t *testing.T) {
+	s := New("", okHandler, WithOutputWriter(io.Discard))
+
+	stopped := make(chan struct{})
+	s.Run(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	})
+	errBoom := errors.New("boom")
+	s.Run(func(ctx context.Context) error { return errBoom })
+
+	_, errc := start(t, s)
+	select {
+	case <-s.Context().Done():
+		t.Fatal("expected the server context to be live while serving")
+	default:
+	}
+
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("expected Serve to wait for the worker to stop")
+	}
+	if err := s.Wait(); !errors.Is(err, errBoom) {
+		t.Errorf("expected Wait to return %v, got %v", errBoom, err)
+	}
+}
+
+func TestRunWorkersShutdownTimeout(t *testing.T) {
+	s := New("", okHandler, WithOutputWriter(io.Discard), WithErrorWriter(io.Discard), WithShutdown(10*time.Millisecond))
+
+	release := make(chan struct{})
+	defer close(release)
+	s.Run(func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	_, errc := start(t, s)
+	s.signals <- syscall.SIGTERM
+	if err := <-errc; !errors.Is(err, ErrShutdownTimeout) {
+		t.Errorf("expected error %v, got %v", ErrShutdownTimeout, err)
+	}
+}
@@ -0,0 +1,25 @@
+package servermetrics_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/haleyrc/http/server"
+	"github.com/haleyrc/http/servermetrics"
+)
+
+func Example() {
+	rec := servermetrics.New()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "hello")
+	})
+	mux.Handle("GET /metrics", rec.Handler())
+
+	s := server.New(":8080", mux, server.WithMetrics(rec))
+	if err := s.ListenAndServe(context.Background()); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package servermetrics provides a server.MetricsRecorder backed by the
+// Prometheus client library.
+//
+// A Recorder exports the standard RED metrics for every request the server
+// handles, labeled by method, route and status class:
+//
+//	http_server_requests_total
+//	http_server_errors_total
+//	http_server_request_duration_seconds
+//
+// the distributions of body sizes, labeled by route:
+//
+//	http_server_request_size_bytes
+//	http_server_response_size_bytes
+//
+// along with http_server_tls_handshake_errors_total, labeled by kind. The
+// route label is the ServeMux pattern that matched the request, or "unmatched"
+// for requests no pattern matched, so label cardinality stays bounded by the
+// number of routes.
+package servermetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// UnmatchedRoute is the route label used for requests that were not routed by
+// a ServeMux pattern.
+const UnmatchedRoute = "unmatched"
+
+// Recorder records server metrics to a Prometheus registry. It implements
+// server.MetricsRecorder, server.RequestRecorder and server.SizeRecorder.
+type Recorder struct {
+	registry *prometheus.Registry
+
+	requests   *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	reqSize    *prometheus.HistogramVec
+	respSize   *prometheus.HistogramVec
+	handshakes *prometheus.CounterVec
+}
+
+// sizeBuckets range from 64B to 16MiB.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+
+// New returns a Recorder with its metrics registered to a new registry, which
+// also includes the standard Go runtime and process collectors.
+func New() *Recorder {
+	labels := []string{"method", "route", "status_class"}
+	r := &Recorder{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_requests_total",
+			Help: "Total number of HTTP requests handled.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_errors_total",
+			Help: "Total number of HTTP requests that resulted in a 5xx response.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		reqSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_request_size_bytes",
+			Help:    "Size of HTTP request bodies read.",
+			Buckets: sizeBuckets,
+		}, []string{"route"}),
+		respSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_response_size_bytes",
+			Help:    "Size of HTTP response bodies written.",
+			Buckets: sizeBuckets,
+		}, []string{"route"}),
+		handshakes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_tls_handshake_errors_total",
+			Help: "Total number of failed TLS handshakes.",
+		}, []string{"kind"}),
+	}
+	r.registry.MustRegister(
+		r.requests,
+		r.errors,
+		r.duration,
+		r.reqSize,
+		r.respSize,
+		r.handshakes,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// Registry returns the registry the Recorder's metrics are registered to, so
+// applications can register their own metrics alongside them.
+func (r *Recorder) Registry() *prometheus.Registry {
+	return r.registry
+}
+
+// Handler returns a handler that serves the registry's metrics in the
+// Prometheus exposition format, for mounting at /metrics.
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+}
+
+// RecordRequest implements server.RequestRecorder.
+func (r *Recorder) RecordRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	labels := prometheus.Labels{
+		"method":       normalizeMethod(method),
+		"route":        route,
+		"status_class": statusClass(status),
+	}
+	r.requests.With(labels).Inc()
+	if status >= 500 {
+		r.errors.With(labels).Inc()
+	}
+	r.duration.With(labels).Observe(duration.Seconds())
+}
+
+// ObserveSizes implements server.SizeRecorder.
+func (r *Recorder) ObserveSizes(route string, reqBytes, respBytes int64) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	r.reqSize.WithLabelValues(route).Observe(float64(reqBytes))
+	r.respSize.WithLabelValues(route).Observe(float64(respBytes))
+}
+
+// RecordTLSHandshakeError implements server.MetricsRecorder.
+func (r *Recorder) RecordTLSHandshakeError(kind string) {
+	r.handshakes.WithLabelValues(kind).Inc()
+}
+
+// normalizeMethod maps methods other than the standard ones to "other", since
+// clients control the method and could otherwise create unbounded labels.
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodConnect,
+		http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// statusClass returns the class of status, such as "2xx".
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,91 @@
+package servermetrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/haleyrc/http/servermetrics"
+)
+
+func TestRecordRequest(t *testing.T) {
+	rec := servermetrics.New()
+	rec.RecordRequest("GET", "GET /widgets/{id}", http.StatusOK, 10*time.Millisecond)
+	rec.RecordRequest("GET", "GET /widgets/{id}", http.StatusOK, 20*time.Millisecond)
+	rec.RecordRequest("POST", "", http.StatusInternalServerError, time.Millisecond)
+	rec.RecordRequest("BREW", "", http.StatusNotFound, time.Millisecond)
+
+	want := `
+# HELP http_server_errors_total Total number of HTTP requests that resulted in a 5xx response.
+# TYPE http_server_errors_total counter
+http_server_errors_total{method="POST",route="unmatched",status_class="5xx"} 1
+# HELP http_server_requests_total Total number of HTTP requests handled.
+# TYPE http_server_requests_total counter
+http_server_requests_total{method="GET",route="GET /widgets/{id}",status_class="2xx"} 2
+http_server_requests_total{method="POST",route="unmatched",status_class="5xx"} 1
+http_server_requests_total{method="other",route="unmatched",status_class="4xx"} 1
+`
+	err := testutil.GatherAndCompare(rec.Registry(), strings.NewReader(want),
+		"http_server_requests_total", "http_server_errors_total")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if n := testutil.CollectAndCount(rec.Registry(), "http_server_request_duration_seconds"); n != 3 {
+		t.Errorf("expected 3 duration series, got %d", n)
+	}
+}
+
+func TestObserveSizes(t *testing.T) {
+	rec := servermetrics.New()
+	rec.ObserveSizes("POST /upload", 1000, 10)
+	rec.ObserveSizes("", 0, 19)
+
+	for _, name := range []string{"http_server_request_size_bytes", "http_server_response_size_bytes"} {
+		if n := testutil.CollectAndCount(rec.Registry(), name); n != 2 {
+			t.Errorf("expected 2 %s series, got %d", name, n)
+		}
+	}
+}
+
+func TestRecordTLSHandshakeError(t *testing.T) {
+	rec := servermetrics.New()
+	rec.RecordTLSHandshakeError("http_to_https")
+
+	want := `
+# HELP http_server_tls_handshake_errors_total Total number of failed TLS handshakes.
+# TYPE http_server_tls_handshake_errors_total counter
+http_server_tls_handshake_errors_total{kind="http_to_https"} 1
+`
+	err := testutil.GatherAndCompare(rec.Registry(), strings.NewReader(want),
+		"http_server_tls_handshake_errors_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := servermetrics.New()
+	rec.RecordRequest("GET", "GET /", http.StatusOK, time.Millisecond)
+
+	w := httptest.NewRecorder()
+	rec.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`http_server_requests_total{method="GET",route="GET /",status_class="2xx"} 1`,
+		`http_server_request_duration_seconds_count{method="GET",route="GET /",status_class="2xx"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %q", want)
+		}
+	}
+}